@@ -0,0 +1,43 @@
+// Command testground-reap removes containers and networks left behind by
+// testground test binaries that crashed or were killed.
+//
+// Usage:
+//
+//	testground-reap [-older-than 1h] [-timeout 2m]
+//
+// Run it periodically on CI runners, e.g. before every job.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dsvdev/testground"
+)
+
+func main() {
+	olderThan := flag.Duration("older-than", time.Hour, "remove resources of sessions idle for longer than this")
+	timeout := flag.Duration("timeout", 2*time.Minute, "overall timeout")
+	flag.Parse()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	res, err := testground.Reap(ctx, *olderThan)
+	if res != nil {
+		for _, id := range res.Containers {
+			fmt.Printf("removed container %s\n", id)
+		}
+		for _, name := range res.Networks {
+			fmt.Printf("removed network %s\n", name)
+		}
+		fmt.Printf("reaped %d container(s), %d network(s)\n", len(res.Containers), len(res.Networks))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "testground-reap: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,357 @@
+package testground
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Component is anything an Environment can start and terminate. It has the
+// same shape as suite.Managed, so every container type satisfies it.
+type Component interface {
+	Terminate(ctx context.Context) error
+}
+
+// StartFunc starts a single component. Components it depends on are already
+// running and can be looked up with Get. If StartFunc returns an error it must
+// release whatever it managed to start itself. Returning a nil Component
+// without an error is a start error.
+type StartFunc func(ctx context.Context, env *Environment) (Component, error)
+
+type node struct {
+	name      string
+	start     StartFunc
+	dependsOn []string
+}
+
+// Environment starts a graph of named components in dependency order.
+// Components without a dependency between them start in parallel. On failure,
+// and on Terminate, everything that was started is terminated in reverse
+// dependency order. An Environment is itself a Component, so it can be
+// registered with a suite as a single unit.
+type Environment struct {
+	mu      sync.Mutex
+	nodes   []*node
+	byName  map[string]*node
+	errs    []error
+	started bool
+
+	running    map[string]Component
+	startOrder []string
+
+	netMu   sync.Mutex
+	network *Network
+}
+
+func NewEnvironment() *Environment {
+	return &Environment{
+		byName:  make(map[string]*node),
+		running: make(map[string]Component),
+	}
+}
+
+// Add declares a component named name that is started by start once every
+// component listed in dependsOn is running. Declaration errors such as a
+// duplicate name are reported by Start.
+func (e *Environment) Add(name string, start StartFunc, dependsOn ...string) *Environment {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.started {
+		panic("testground: Environment.Add must be called before Start")
+	}
+	if _, ok := e.byName[name]; ok {
+		e.errs = append(e.errs, fmt.Errorf("duplicate component %q", name))
+		return e
+	}
+	n := &node{name: name, start: start, dependsOn: dependsOn}
+	e.nodes = append(e.nodes, n)
+	e.byName[name] = n
+	return e
+}
+
+// Start validates the graph and starts all components. If any component fails
+// to start or ctx is cancelled, the components that did start are terminated
+// in reverse dependency order and the joined errors are returned.
+func (e *Environment) Start(ctx context.Context) error {
+	e.mu.Lock()
+	if e.started {
+		e.mu.Unlock()
+		return errors.New("environment: already started")
+	}
+	e.started = true
+	e.mu.Unlock()
+
+	if err := e.validate(); err != nil {
+		return fmt.Errorf("environment: %w", err)
+	}
+
+	startCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(map[string]chan struct{}, len(e.nodes))
+	for _, n := range e.nodes {
+		done[n.name] = make(chan struct{})
+	}
+
+	var (
+		wg       sync.WaitGroup
+		errMu    sync.Mutex
+		startErr []error
+	)
+	for _, n := range e.nodes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(done[n.name])
+
+			for _, dep := range n.dependsOn {
+				select {
+				case <-done[dep]:
+				case <-startCtx.Done():
+					return
+				}
+				if _, ok := e.lookup(dep); !ok {
+					return // dependency failed, its error is already recorded
+				}
+			}
+
+			c, err := n.start(startCtx, e)
+			if err == nil && c == nil {
+				err = errors.New("returned nil component")
+			}
+			if err != nil {
+				errMu.Lock()
+				startErr = append(startErr, fmt.Errorf("start %q: %w", n.name, err))
+				errMu.Unlock()
+				cancel()
+				return
+			}
+
+			e.mu.Lock()
+			e.running[n.name] = c
+			e.startOrder = append(e.startOrder, n.name)
+			e.mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	// Components waiting for a dependency give up without an error when ctx
+	// is cancelled, so the environment may be only partly started.
+	if len(startErr) == 0 && startCtx.Err() != nil {
+		startErr = append(startErr, startCtx.Err())
+	}
+	if len(startErr) > 0 {
+		if err := e.Terminate(context.WithoutCancel(ctx)); err != nil {
+			startErr = append(startErr, fmt.Errorf("cleanup: %w", err))
+		}
+		return fmt.Errorf("environment: %w", errors.Join(startErr...))
+	}
+	return nil
+}
+
+// Terminate stops every running component in reverse dependency order, then
+// removes the shared network if one was created. It returns all errors joined.
+func (e *Environment) Terminate(ctx context.Context) error {
+	e.mu.Lock()
+	order := e.startOrder
+	running := e.running
+	e.startOrder = nil
+	e.running = make(map[string]Component)
+	e.mu.Unlock()
+
+	var errs []error
+	for i := len(order) - 1; i >= 0; i-- {
+		if err := running[order[i]].Terminate(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("terminate %q: %w", order[i], err))
+		}
+	}
+
+	e.netMu.Lock()
+	net := e.network
+	e.network = nil
+	e.netMu.Unlock()
+	if net != nil {
+		if err := net.Terminate(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("terminate network: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Logs returns the output of every running component that can produce logs,
+// in start order, each preceded by a header line with the component name.
+// The caller must close the returned reader.
+func (e *Environment) Logs(ctx context.Context) (io.ReadCloser, error) {
+	return e.logs(ctx, time.Time{})
+}
+
+// LogsSince is like Logs, but returns only the output produced since since
+// by components that have a LogsSince method, and the whole output of the
+// others.
+func (e *Environment) LogsSince(ctx context.Context, since time.Time) (io.ReadCloser, error) {
+	return e.logs(ctx, since)
+}
+
+// logSource and sinceLogSource are implemented by components whose output
+// Logs and LogsSince include.
+type (
+	logSource interface {
+		Logs(ctx context.Context) (io.ReadCloser, error)
+	}
+	sinceLogSource interface {
+		LogsSince(ctx context.Context, since time.Time) (io.ReadCloser, error)
+	}
+)
+
+func (e *Environment) logs(ctx context.Context, since time.Time) (io.ReadCloser, error) {
+	e.mu.Lock()
+	order := slices.Clone(e.startOrder)
+	running := maps.Clone(e.running)
+	e.mu.Unlock()
+
+	var readers []io.Reader
+	var closers []io.Closer
+	for _, name := range order {
+		var rc io.ReadCloser
+		var err error
+		if src, ok := running[name].(sinceLogSource); ok && !since.IsZero() {
+			rc, err = src.LogsSince(ctx, since)
+		} else if src, ok := running[name].(logSource); ok {
+			rc, err = src.Logs(ctx)
+		} else {
+			continue
+		}
+		if err != nil {
+			readers = append(readers, strings.NewReader(fmt.Sprintf("==> %s <==\n(logs unavailable: %v)\n", name, err)))
+			continue
+		}
+		readers = append(readers, strings.NewReader(fmt.Sprintf("==> %s <==\n", name)), rc)
+		closers = append(closers, rc)
+	}
+	return &multiReadCloser{Reader: io.MultiReader(readers...), closers: closers}, nil
+}
+
+// Healthy reports whether every running component that implements
+// HealthChecker is healthy. Wrap it in WaitHealthy to wait for the whole
+// environment, e.g. after restarting one of its components.
+func (e *Environment) Healthy(ctx context.Context) error {
+	e.mu.Lock()
+	order := slices.Clone(e.startOrder)
+	running := maps.Clone(e.running)
+	e.mu.Unlock()
+
+	var errs []error
+	for _, name := range order {
+		if hc, ok := running[name].(HealthChecker); ok {
+			if err := hc.Healthy(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("%q: %w", name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type multiReadCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (m *multiReadCloser) Close() error {
+	var errs []error
+	for _, c := range m.closers {
+		errs = append(errs, c.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Network returns a Docker network shared by all components of the
+// environment, creating it on first use. It is removed by Terminate.
+func (e *Environment) Network(ctx context.Context) (*Network, error) {
+	e.netMu.Lock()
+	defer e.netMu.Unlock()
+	if e.network == nil {
+		net, err := NewNetwork(ctx)
+		if err != nil {
+			return nil, err
+		}
+		e.network = net
+	}
+	return e.network, nil
+}
+
+// Get returns the running component registered under name, asserted to T.
+// Inside a StartFunc it is safe to call for any declared dependency.
+func Get[T Component](e *Environment, name string) (T, error) {
+	var zero T
+	c, ok := e.lookup(name)
+	if !ok {
+		return zero, fmt.Errorf("environment: component %q is not running", name)
+	}
+	v, ok := c.(T)
+	if !ok {
+		return zero, fmt.Errorf("environment: component %q is %T, not %T", name, c, zero)
+	}
+	return v, nil
+}
+
+func (e *Environment) lookup(name string) (Component, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	c, ok := e.running[name]
+	return c, ok
+}
+
+// validate reports declaration errors, unknown dependencies and cycles.
+func (e *Environment) validate() error {
+	errs := e.errs
+	for _, n := range e.nodes {
+		for _, dep := range n.dependsOn {
+			if _, ok := e.byName[dep]; !ok {
+				errs = append(errs, fmt.Errorf("component %q depends on unknown component %q", n.name, dep))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(e.nodes))
+	var path []string
+	var visit func(n *node) error
+	visit = func(n *node) error {
+		switch state[n.name] {
+		case visiting:
+			return fmt.Errorf("dependency cycle: %s -> %s", strings.Join(path, " -> "), n.name)
+		case visited:
+			return nil
+		}
+		state[n.name] = visiting
+		path = append(path, n.name)
+		for _, dep := range n.dependsOn {
+			if err := visit(e.byName[dep]); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[n.name] = visited
+		return nil
+	}
+	for _, n := range e.nodes {
+		if err := visit(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
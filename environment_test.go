@@ -0,0 +1,238 @@
+package testground_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dsvdev/testground"
+)
+
+// fakeComponent records its lifecycle events into a shared log.
+type fakeComponent struct {
+	name string
+	log  *eventLog
+}
+
+func (c *fakeComponent) Terminate(ctx context.Context) error {
+	c.log.add("terminate " + c.name)
+	return nil
+}
+
+type eventLog struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (l *eventLog) add(event string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, event)
+}
+
+func (l *eventLog) index(event string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, e := range l.events {
+		if e == event {
+			return i
+		}
+	}
+	return -1
+}
+
+func starter(log *eventLog, name string) testground.StartFunc {
+	return func(ctx context.Context, env *testground.Environment) (testground.Component, error) {
+		log.add("start " + name)
+		return &fakeComponent{name: name, log: log}, nil
+	}
+}
+
+func TestEnvironment_StartsInDependencyOrder(t *testing.T) {
+	log := &eventLog{}
+	env := testground.NewEnvironment().
+		Add("service", starter(log, "service"), "postgres", "kafka").
+		Add("postgres", starter(log, "postgres")).
+		Add("kafka", starter(log, "kafka"))
+
+	if err := env.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	svc := log.index("start service")
+	if svc < log.index("start postgres") || svc < log.index("start kafka") {
+		t.Errorf("service started before its dependencies: %v", log.events)
+	}
+
+	if err := env.Terminate(context.Background()); err != nil {
+		t.Fatalf("Terminate() error = %v", err)
+	}
+	svc = log.index("terminate service")
+	if svc > log.index("terminate postgres") || svc > log.index("terminate kafka") {
+		t.Errorf("service terminated after its dependencies: %v", log.events)
+	}
+}
+
+func TestEnvironment_IndependentComponentsStartInParallel(t *testing.T) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	both := make(chan struct{})
+	go func() { wg.Wait(); close(both) }()
+
+	waitForBoth := func(ctx context.Context, env *testground.Environment) (testground.Component, error) {
+		wg.Done()
+		select {
+		case <-both:
+			return &fakeComponent{log: &eventLog{}}, nil
+		case <-time.After(5 * time.Second):
+			return nil, errors.New("components did not start concurrently")
+		}
+	}
+
+	env := testground.NewEnvironment().
+		Add("a", waitForBoth).
+		Add("b", waitForBoth)
+
+	if err := env.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+}
+
+func TestEnvironment_FailureTearsDownStarted(t *testing.T) {
+	log := &eventLog{}
+	errBoom := errors.New("boom")
+
+	env := testground.NewEnvironment().
+		Add("postgres", starter(log, "postgres")).
+		Add("migrations", func(ctx context.Context, env *testground.Environment) (testground.Component, error) {
+			return nil, errBoom
+		}, "postgres").
+		Add("service", starter(log, "service"), "migrations")
+
+	err := env.Start(context.Background())
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("Start() error = %v, want %v", err, errBoom)
+	}
+	if log.index("start service") >= 0 {
+		t.Error("service started although its dependency failed")
+	}
+	if log.index("terminate postgres") < 0 {
+		t.Errorf("postgres was not terminated: %v", log.events)
+	}
+}
+
+func TestEnvironment_NilComponentIsStartError(t *testing.T) {
+	log := &eventLog{}
+
+	env := testground.NewEnvironment().
+		Add("postgres", starter(log, "postgres")).
+		Add("service", func(ctx context.Context, env *testground.Environment) (testground.Component, error) {
+			return nil, nil
+		}, "postgres")
+
+	err := env.Start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), `start "service": returned nil component`) {
+		t.Fatalf("Start() error = %v, want nil component error", err)
+	}
+	if log.index("terminate postgres") < 0 {
+		t.Errorf("postgres was not terminated: %v", log.events)
+	}
+}
+
+func TestEnvironment_CancelTearsDownStarted(t *testing.T) {
+	log := &eventLog{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	env := testground.NewEnvironment().
+		Add("postgres", func(ctx context.Context, env *testground.Environment) (testground.Component, error) {
+			// The caller gives up while postgres is starting; service, which
+			// waits for it, stops waiting.
+			cancel()
+			time.Sleep(50 * time.Millisecond)
+			log.add("start postgres")
+			return &fakeComponent{name: "postgres", log: log}, nil
+		}).
+		Add("service", starter(log, "service"), "postgres")
+
+	err := env.Start(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Start() error = %v, want %v", err, context.Canceled)
+	}
+	if log.index("start service") >= 0 {
+		t.Error("service started after the context was cancelled")
+	}
+	if log.index("terminate postgres") < 0 {
+		t.Errorf("postgres was not terminated: %v", log.events)
+	}
+}
+
+func TestEnvironment_Get(t *testing.T) {
+	log := &eventLog{}
+	var got *fakeComponent
+
+	env := testground.NewEnvironment().
+		Add("postgres", starter(log, "postgres")).
+		Add("service", func(ctx context.Context, env *testground.Environment) (testground.Component, error) {
+			pg, err := testground.Get[*fakeComponent](env, "postgres")
+			if err != nil {
+				return nil, err
+			}
+			got = pg
+			return &fakeComponent{name: "service", log: log}, nil
+		}, "postgres")
+
+	if err := env.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if got == nil || got.name != "postgres" {
+		t.Errorf("Get() = %+v, want postgres component", got)
+	}
+
+	if _, err := testground.Get[*fakeComponent](env, "missing"); err == nil {
+		t.Error("Get() of unknown component: expected error")
+	}
+}
+
+func TestEnvironment_InvalidGraph(t *testing.T) {
+	log := &eventLog{}
+	tests := []struct {
+		name string
+		env  *testground.Environment
+		want string
+	}{
+		{
+			name: "unknown dependency",
+			env:  testground.NewEnvironment().Add("a", starter(log, "a"), "b"),
+			want: "unknown component",
+		},
+		{
+			name: "duplicate",
+			env:  testground.NewEnvironment().Add("a", starter(log, "a")).Add("a", starter(log, "a")),
+			want: "duplicate",
+		},
+		{
+			name: "cycle",
+			env: testground.NewEnvironment().
+				Add("a", starter(log, "a"), "b").
+				Add("b", starter(log, "b"), "a"),
+			want: "cycle",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.env.Start(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Start() error = %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+
+	if len(log.events) != 0 {
+		t.Errorf("components started for an invalid graph: %v", log.events)
+	}
+}
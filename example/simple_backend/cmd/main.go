@@ -7,7 +7,9 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
 
 	"github.com/dsvdev/testground/example/simple_backend/handler"
 	"github.com/dsvdev/testground/example/simple_backend/repository"
@@ -71,6 +73,19 @@ func main() {
 		json.NewEncoder(w).Encode(newUser)
 	})
 
+	// Shut down cleanly on SIGTERM so that coverage counters are written when
+	// the service runs under service.WithCoverage.
+	sigCtx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{Addr: ":8080", Handler: r}
+	go func() {
+		<-sigCtx.Done()
+		srv.Shutdown(context.Background())
+	}()
+
 	fmt.Println("Listening on :8080")
-	log.Fatal(http.ListenAndServe(":8080", r))
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
 }
@@ -27,43 +27,24 @@ func TestMain(m *testing.M) {
 	ctx := context.Background()
 	s := suite.NewMain(m)
 
-	net, err := testground.NewNetwork(ctx)
-	if err != nil {
-		fmt.Printf("failed to create network: %v\n", err)
+	env := testground.NewEnvironment().
+		Add("postgres", startPostgres).
+		Add("service", startService, "postgres")
+	if err := env.Start(ctx); err != nil {
+		fmt.Printf("failed to start environment: %v\n", err)
 		os.Exit(1)
 	}
-	s.Add(net)
+	s.Add(env)
 
-	pgContainer, err = postgres.New(ctx,
-		postgres.WithNetwork(net),
-		postgres.WithNetworkAlias("postgres"),
-	)
-	if err != nil {
-		fmt.Printf("failed to start postgres: %v\n", err)
-		s.Cleanup()
-		os.Exit(1)
-	}
-	s.Add(pgContainer)
-
-	if err = runMigrations(ctx); err != nil {
-		fmt.Printf("failed to run migrations: %v\n", err)
-		s.Cleanup()
-		os.Exit(1)
+	var err error
+	if pgContainer, err = testground.Get[*postgres.Container](env, "postgres"); err == nil {
+		svcContainer, err = testground.Get[*service.Container](env, "service")
 	}
-
-	svcContainer, err = service.New(ctx,
-		service.WithBuildContext("../../../"),
-		service.WithDockerfile("example/simple_backend/Dockerfile"),
-		service.WithNetwork(net),
-		service.WithEnv("DATABASE_URL", pgContainer.NetworkConnectionString()),
-		service.WithPort("8080"),
-	)
 	if err != nil {
-		fmt.Printf("failed to start service: %v\n", err)
-		s.Cleanup()
+		fmt.Printf("failed to look up components: %v\n", err)
+		env.Terminate(ctx) //nolint:errcheck
 		os.Exit(1)
 	}
-	s.Add(svcContainer)
 
 	client = httpclient.New(httpclient.WithBaseURL(svcContainer.URL()))
 	apiKey := os.Getenv("ANTHROPIC_API_KEY")
@@ -75,15 +56,52 @@ func TestMain(m *testing.M) {
 	os.Exit(s.Run())
 }
 
-func runMigrations(ctx context.Context) error {
-	conn, err := pgContainer.Conn(ctx)
+func startPostgres(ctx context.Context, env *testground.Environment) (testground.Component, error) {
+	net, err := env.Network(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pg, err := postgres.New(ctx,
+		postgres.WithNetwork(net),
+		postgres.WithNetworkAlias("postgres"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := pg.Migrate("../migrations").Run(ctx); err != nil {
+		pg.Terminate(ctx) //nolint:errcheck
+		return nil, fmt.Errorf("run migrations: %w", err)
+	}
+	return pg, nil
+}
+
+func startService(ctx context.Context, env *testground.Environment) (testground.Component, error) {
+	net, err := env.Network(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pg, err := testground.Get[*postgres.Container](env, "postgres")
+	if err != nil {
+		return nil, err
+	}
+	opts := []service.Option{
+		service.WithBuildContext("../../../"),
+		service.WithDockerfile("example/simple_backend/Dockerfile"),
+		service.WithNetwork(net),
+		service.WithEnv("DATABASE_URL", pg.NetworkConnectionString()),
+		service.WithPort("8080"),
+	}
+	// INTEGRATION_COVERPROFILE=cover.out go test ./... collects the coverage
+	// of the service itself; view it with go tool cover -html=cover.out.
+	if profile := os.Getenv("INTEGRATION_COVERPROFILE"); profile != "" {
+		opts = append(opts,
+			service.WithGoPackage("../cmd"),
+			service.WithCoverage(profile),
+		)
+	}
+	svc, err := service.New(ctx, opts...)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer conn.Close(ctx)
-	_, err = conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS users (
-		id   BIGSERIAL PRIMARY KEY,
-		name TEXT NOT NULL
-	)`)
-	return err
+	return svc, nil
 }
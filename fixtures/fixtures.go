@@ -0,0 +1,124 @@
+// Package fixtures loads declarative test data from YAML or JSON files and
+// turns it into a testground.Precondition.
+//
+// A fixture file lists table rows and topic messages:
+//
+//	tables:
+//	  users:
+//	    - id: 1
+//	      name: "{{string 8}}"
+//	  orders:
+//	    - user_id: 1
+//	      external_id: "{{uuid}}"
+//	      amount: "{{int 1 100}}"
+//	topics:
+//	  events:
+//	    - '{"type": "created"}'
+//	    - type: updated
+//	      id: "{{uuid}}"
+//
+// String values are Go templates with the faker helpers available as
+// functions. Templates are rendered every time the precondition is applied,
+// so each test gets fresh values.
+package fixtures
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Fixture is the parsed content of a fixture file.
+type Fixture struct {
+	Tables []Table
+	Topics []Topic
+}
+
+// Table holds the rows to insert into a single table. Each row maps a column
+// name to its value.
+type Table struct {
+	Name string
+	Rows []map[string]any
+}
+
+// Topic holds the messages to publish to a single Kafka topic. A message is
+// either a string, published as is, or a structured value, published as JSON.
+type Topic struct {
+	Name     string
+	Messages []any
+}
+
+// Parse decodes a fixture from YAML. Since JSON is a subset of YAML, JSON
+// fixtures are accepted as well. Tables and topics keep the order in which
+// they appear in the document.
+func Parse(data []byte) (*Fixture, error) {
+	var doc struct {
+		Tables yaml.Node `yaml:"tables"`
+		Topics yaml.Node `yaml:"topics"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("fixtures: parse: %w", err)
+	}
+
+	var f Fixture
+	if err := eachEntry(&doc.Tables, func(name string, value *yaml.Node) error {
+		var rows []map[string]any
+		if err := value.Decode(&rows); err != nil {
+			return fmt.Errorf("table %q: %w", name, err)
+		}
+		f.Tables = append(f.Tables, Table{Name: name, Rows: rows})
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("fixtures: parse: %w", err)
+	}
+
+	if err := eachEntry(&doc.Topics, func(name string, value *yaml.Node) error {
+		var msgs []any
+		if err := value.Decode(&msgs); err != nil {
+			return fmt.Errorf("topic %q: %w", name, err)
+		}
+		f.Topics = append(f.Topics, Topic{Name: name, Messages: msgs})
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("fixtures: parse: %w", err)
+	}
+
+	return &f, nil
+}
+
+// ParseFile reads and parses the fixture file at path.
+func ParseFile(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("fixtures: %w", err)
+	}
+	return Parse(data)
+}
+
+// ParseFS reads and parses the fixture file at path within fsys.
+func ParseFS(fsys fs.FS, path string) (*Fixture, error) {
+	data, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return nil, fmt.Errorf("fixtures: %w", err)
+	}
+	return Parse(data)
+}
+
+// eachEntry walks a YAML mapping node in document order. A zero node (the key
+// is absent from the document) is treated as an empty mapping.
+func eachEntry(node *yaml.Node, fn func(name string, value *yaml.Node) error) error {
+	if node.Kind == 0 {
+		return nil
+	}
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: expected a mapping", node.Line)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if err := fn(node.Content[i].Value, node.Content[i+1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
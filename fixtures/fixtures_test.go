@@ -0,0 +1,146 @@
+package fixtures_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/dsvdev/testground"
+	"github.com/dsvdev/testground/fixtures"
+	"github.com/dsvdev/testground/services/kafka"
+	"github.com/dsvdev/testground/services/postgres"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestParse_KeepsDocumentOrder(t *testing.T) {
+	f, err := fixtures.ParseFile("testdata/shop.yaml")
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+
+	if len(f.Tables) != 2 || f.Tables[0].Name != "orders" || f.Tables[1].Name != "users" {
+		t.Fatalf("tables = %+v, want [orders users]", f.Tables)
+	}
+	if len(f.Topics) != 1 || len(f.Topics[0].Messages) != 2 {
+		t.Fatalf("topics = %+v, want one topic with 2 messages", f.Topics)
+	}
+}
+
+func TestParse_JSON(t *testing.T) {
+	f, err := fixtures.Parse([]byte(`{"tables": {"users": [{"id": 1, "name": "Alice"}]}}`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(f.Tables) != 1 || f.Tables[0].Rows[0]["name"] != "Alice" {
+		t.Fatalf("tables = %+v, want users with Alice", f.Tables)
+	}
+}
+
+func TestParse_InvalidShape(t *testing.T) {
+	if _, err := fixtures.Parse([]byte(`tables: [users]`)); err == nil {
+		t.Fatal("Parse() expected error for a list of tables")
+	}
+}
+
+func TestRender_Templates(t *testing.T) {
+	f, err := fixtures.ParseFile("testdata/shop.yaml")
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+	r, err := f.Render()
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	order := r.Tables[0].Rows[0]
+	if id, ok := order["external_id"].(string); !ok || !uuidPattern.MatchString(id) {
+		t.Errorf("external_id = %v, want a UUID", order["external_id"])
+	}
+	if amount, ok := order["amount"].(int64); !ok || amount < 1 || amount > 100 {
+		t.Errorf("amount = %#v, want int64 in [1, 100]", order["amount"])
+	}
+	if name, ok := r.Tables[1].Rows[0]["name"].(string); !ok || len(name) != 8 {
+		t.Errorf("name = %#v, want 8-letter string", r.Tables[1].Rows[0]["name"])
+	}
+
+	// The source fixture stays untouched so it can be rendered again.
+	if f.Tables[0].Rows[0]["external_id"] != "{{uuid}}" {
+		t.Errorf("source fixture was modified: %v", f.Tables[0].Rows[0])
+	}
+}
+
+func TestRender_OnlyIntActionsBecomeIntegers(t *testing.T) {
+	f, err := fixtures.Parse([]byte(`tables: {users: [{id: " {{int64 7 7}} ", code: '{{"42"}}', count: "{{print 7}}", n: "{{int 3 3}}{{int 4 4}}"}]}`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	r, err := f.Render()
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	row := r.Tables[0].Rows[0]
+	for col, want := range map[string]any{"id": int64(7), "code": "42", "count": "7", "n": "34"} {
+		if row[col] != want {
+			t.Errorf("%s = %#v, want %#v", col, row[col], want)
+		}
+	}
+}
+
+func TestRender_UnknownFunction(t *testing.T) {
+	f, err := fixtures.Parse([]byte(`tables: {users: [{name: "{{nope}}"}]}`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if _, err := f.Render(); err == nil {
+		t.Fatal("Render() expected error for unknown function")
+	}
+}
+
+func TestLoad_InsertsAndPublishes(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	defer cancel()
+
+	pg, err := postgres.New(ctx)
+	if err != nil {
+		t.Fatalf("postgres.New() error = %v", err)
+	}
+	t.Cleanup(func() { pg.Terminate(context.Background()) })
+
+	kc, err := kafka.New(ctx)
+	if err != nil {
+		t.Fatalf("kafka.New() error = %v", err)
+	}
+	t.Cleanup(func() { kc.Terminate(context.Background()) })
+
+	testground.Apply(t,
+		pg.Exec(`CREATE TABLE users (id BIGINT PRIMARY KEY, name TEXT NOT NULL)`),
+		pg.Exec(`CREATE TABLE orders (
+			id          BIGINT PRIMARY KEY,
+			user_id     BIGINT NOT NULL REFERENCES users (id),
+			external_id UUID NOT NULL,
+			amount      INT NOT NULL
+		)`),
+		fixtures.Load("testdata/shop.yaml",
+			fixtures.WithPostgres(pg),
+			fixtures.WithKafka(kc),
+		),
+	)
+
+	pool, err := pg.Pool(ctx)
+	if err != nil {
+		t.Fatalf("Pool() error = %v", err)
+	}
+	var count int
+	if err := pool.QueryRow(ctx, `SELECT COUNT(*) FROM orders JOIN users ON users.id = orders.user_id`).Scan(&count); err != nil {
+		t.Fatalf("SELECT COUNT(*) error = %v", err)
+	}
+	if count != 1 {
+		t.Errorf("count = %d, want 1", count)
+	}
+
+	kc.AssertMessageCount(t, "orders", 2)
+	kc.AssertHasMessageContaining(t, "orders", `"paid"`, 1)
+}
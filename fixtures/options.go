@@ -0,0 +1,27 @@
+package fixtures
+
+import (
+	"github.com/dsvdev/testground/services/kafka"
+	"github.com/dsvdev/testground/services/postgres"
+)
+
+type config struct {
+	pg *postgres.Container
+	kc *kafka.Container
+}
+
+type Option func(*config)
+
+// WithPostgres sets the container that receives the fixture's table rows.
+func WithPostgres(pg *postgres.Container) Option {
+	return func(c *config) {
+		c.pg = pg
+	}
+}
+
+// WithKafka sets the container that receives the fixture's topic messages.
+func WithKafka(kc *kafka.Container) Option {
+	return func(c *config) {
+		c.kc = kc
+	}
+}
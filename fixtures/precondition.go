@@ -0,0 +1,218 @@
+package fixtures
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/dsvdev/testground"
+)
+
+type fixturePrecondition struct {
+	load func() (*Fixture, error)
+	cfg  config
+}
+
+// Load returns a Precondition that reads the fixture file at path, inserts
+// its table rows and publishes its topic messages. The file is read and its
+// templates are rendered on every Apply.
+func Load(path string, opts ...Option) testground.Precondition {
+	return newPrecondition(func() (*Fixture, error) { return ParseFile(path) }, opts)
+}
+
+// LoadFS is like Load but reads the file from fsys, e.g. an embed.FS.
+func LoadFS(fsys fs.FS, path string, opts ...Option) testground.Precondition {
+	return newPrecondition(func() (*Fixture, error) { return ParseFS(fsys, path) }, opts)
+}
+
+// Precondition returns a Precondition that inserts the fixture's table rows
+// and publishes its topic messages. Templates are rendered on every Apply.
+func (f *Fixture) Precondition(opts ...Option) testground.Precondition {
+	return newPrecondition(func() (*Fixture, error) { return f, nil }, opts)
+}
+
+func newPrecondition(load func() (*Fixture, error), opts []Option) *fixturePrecondition {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &fixturePrecondition{load: load, cfg: cfg}
+}
+
+func (p *fixturePrecondition) Apply(ctx context.Context, t *testing.T) error {
+	f, err := p.load()
+	if err != nil {
+		return err
+	}
+	f, err = f.Render()
+	if err != nil {
+		return err
+	}
+
+	if len(f.Tables) > 0 {
+		if p.cfg.pg == nil {
+			return fmt.Errorf("fixtures: file has tables but no postgres container is set (use WithPostgres)")
+		}
+		if err := p.insertTables(ctx, t, f.Tables); err != nil {
+			return err
+		}
+	}
+
+	if len(f.Topics) > 0 {
+		if p.cfg.kc == nil {
+			return fmt.Errorf("fixtures: file has topics but no kafka container is set (use WithKafka)")
+		}
+		if err := p.publishTopics(ctx, t, f.Topics); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *fixturePrecondition) insertTables(ctx context.Context, t *testing.T, tables []Table) error {
+	deps, err := p.foreignKeys(ctx)
+	if err != nil {
+		return err
+	}
+	tables, err = sortTables(tables, deps)
+	if err != nil {
+		return err
+	}
+
+	for _, tbl := range tables {
+		for i, row := range tbl.Rows {
+			sql, args := insertStatement(tbl.Name, row)
+			if err := p.cfg.pg.Exec(sql, args).Apply(ctx, t); err != nil {
+				return fmt.Errorf("fixtures: table %q row %d: %w", tbl.Name, i, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (p *fixturePrecondition) publishTopics(ctx context.Context, t *testing.T, topics []Topic) error {
+	for _, topic := range topics {
+		for i, msg := range topic.Messages {
+			var value []byte
+			if s, ok := msg.(string); ok {
+				value = []byte(s)
+			} else {
+				b, err := json.Marshal(msg)
+				if err != nil {
+					return fmt.Errorf("fixtures: topic %q message %d: %w", topic.Name, i, err)
+				}
+				value = b
+			}
+			if err := p.cfg.kc.Publish(topic.Name, value).Apply(ctx, t); err != nil {
+				return fmt.Errorf("fixtures: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// foreignKeys returns, for every table that has a foreign key, the tables it
+// references. Each table is listed both by its bare and schema-qualified name.
+func (p *fixturePrecondition) foreignKeys(ctx context.Context) (map[string][]string, error) {
+	pool, err := p.cfg.pg.Pool(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fixtures: connect: %w", err)
+	}
+
+	rows, err := pool.Query(ctx, `
+		SELECT cn.nspname, c.relname, pn.nspname, pc.relname
+		FROM pg_constraint con
+		JOIN pg_class c      ON c.oid = con.conrelid
+		JOIN pg_namespace cn ON cn.oid = c.relnamespace
+		JOIN pg_class pc     ON pc.oid = con.confrelid
+		JOIN pg_namespace pn ON pn.oid = pc.relnamespace
+		WHERE con.contype = 'f'
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("fixtures: query foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	deps := make(map[string][]string)
+	for rows.Next() {
+		var childSchema, child, parentSchema, parent string
+		if err := rows.Scan(&childSchema, &child, &parentSchema, &parent); err != nil {
+			return nil, fmt.Errorf("fixtures: query foreign keys: %w", err)
+		}
+		for _, name := range []string{child, childSchema + "." + child} {
+			deps[name] = append(deps[name], parent, parentSchema+"."+parent)
+		}
+	}
+	return deps, rows.Err()
+}
+
+// sortTables orders tables so that every table comes after the tables it
+// references. Tables without a dependency between them keep the order from
+// the fixture file.
+func sortTables(tables []Table, deps map[string][]string) ([]Table, error) {
+	pending := slices.Clone(tables)
+	sorted := make([]Table, 0, len(tables))
+	done := make(map[string]bool, len(tables))
+
+	inFixture := make(map[string]bool, len(tables))
+	for _, tbl := range tables {
+		inFixture[tbl.Name] = true
+	}
+
+	ready := func(tbl Table) bool {
+		for _, parent := range deps[tbl.Name] {
+			if parent != tbl.Name && inFixture[parent] && !done[parent] {
+				return false
+			}
+		}
+		return true
+	}
+
+	for len(pending) > 0 {
+		i := slices.IndexFunc(pending, ready)
+		if i < 0 {
+			names := make([]string, len(pending))
+			for j, tbl := range pending {
+				names[j] = tbl.Name
+			}
+			return nil, fmt.Errorf("fixtures: cyclic foreign keys between tables %s", strings.Join(names, ", "))
+		}
+		done[pending[i].Name] = true
+		sorted = append(sorted, pending[i])
+		pending = slices.Delete(pending, i, i+1)
+	}
+	return sorted, nil
+}
+
+// insertStatement builds a parameterized INSERT for row. Columns are sorted
+// so that the statement text is stable across runs.
+func insertStatement(table string, row map[string]any) (string, pgx.NamedArgs) {
+	cols := make([]string, 0, len(row))
+	for col := range row {
+		cols = append(cols, col)
+	}
+	sort.Strings(cols)
+
+	quoted := make([]string, len(cols))
+	params := make([]string, len(cols))
+	args := make(pgx.NamedArgs, len(cols))
+	for i, col := range cols {
+		quoted[i] = pgx.Identifier{col}.Sanitize()
+		params[i] = fmt.Sprintf("@p%d", i)
+		args[fmt.Sprintf("p%d", i)] = row[col]
+	}
+
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		pgx.Identifier(strings.Split(table, ".")).Sanitize(),
+		strings.Join(quoted, ", "),
+		strings.Join(params, ", "),
+	), args
+}
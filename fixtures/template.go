@@ -0,0 +1,137 @@
+package fixtures
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/dsvdev/testground/faker"
+)
+
+var funcs = template.FuncMap{
+	"uuid":   faker.RandomUUID,
+	"int":    faker.RandomInt,
+	"int64":  faker.RandomInt64,
+	"string": faker.RandomString,
+}
+
+// Render returns a copy of the fixture with every template evaluated. A string
+// that consists of a single int or int64 action, such as "{{int 1 100}}",
+// becomes an int64 so that it binds to integer columns; every other template
+// renders to a string, even if the output looks like a number.
+func (f *Fixture) Render() (*Fixture, error) {
+	out := &Fixture{
+		Tables: make([]Table, 0, len(f.Tables)),
+		Topics: make([]Topic, 0, len(f.Topics)),
+	}
+
+	for _, tbl := range f.Tables {
+		rows := make([]map[string]any, 0, len(tbl.Rows))
+		for i, row := range tbl.Rows {
+			rendered, err := render(row)
+			if err != nil {
+				return nil, fmt.Errorf("fixtures: table %q row %d: %w", tbl.Name, i, err)
+			}
+			rows = append(rows, rendered.(map[string]any))
+		}
+		out.Tables = append(out.Tables, Table{Name: tbl.Name, Rows: rows})
+	}
+
+	for _, topic := range f.Topics {
+		msgs := make([]any, 0, len(topic.Messages))
+		for i, msg := range topic.Messages {
+			rendered, err := render(msg)
+			if err != nil {
+				return nil, fmt.Errorf("fixtures: topic %q message %d: %w", topic.Name, i, err)
+			}
+			msgs = append(msgs, rendered)
+		}
+		out.Topics = append(out.Topics, Topic{Name: topic.Name, Messages: msgs})
+	}
+
+	return out, nil
+}
+
+// render walks v and evaluates every string it contains as a template.
+func render(v any) (any, error) {
+	switch v := v.(type) {
+	case string:
+		return renderString(v)
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, val := range v {
+			r, err := render(val)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", k, err)
+			}
+			out[k] = r
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(v))
+		for i, val := range v {
+			r, err := render(val)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			out[i] = r
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+func renderString(s string) (any, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+
+	tmpl, err := template.New("").Funcs(funcs).Option("missingkey=error").Parse(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, nil); err != nil {
+		return nil, err
+	}
+
+	out := sb.String()
+	if intAction(tmpl.Tree) {
+		if n, err := strconv.ParseInt(strings.TrimSpace(out), 10, 64); err == nil {
+			return n, nil
+		}
+	}
+	return out, nil
+}
+
+// intAction reports whether tree is a single action, surrounded by nothing
+// but white space, whose pipeline ends with the int or int64 function.
+func intAction(tree *parse.Tree) bool {
+	var action *parse.ActionNode
+	for _, node := range tree.Root.Nodes {
+		switch node := node.(type) {
+		case *parse.TextNode:
+			if len(bytes.TrimSpace(node.Text)) > 0 {
+				return false
+			}
+		case *parse.ActionNode:
+			if action != nil {
+				return false
+			}
+			action = node
+		default:
+			return false
+		}
+	}
+	if action == nil || len(action.Pipe.Decl) > 0 {
+		return false
+	}
+	cmd := action.Pipe.Cmds[len(action.Pipe.Cmds)-1]
+	ident, ok := cmd.Args[0].(*parse.IdentifierNode)
+	return ok && (ident.Ident == "int" || ident.Ident == "int64")
+}
@@ -0,0 +1,52 @@
+package testground
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// HealthChecker is a component that can report whether it is ready to serve.
+// Healthy returns nil when it is, and the reason when it is not. All
+// container types in this module implement it.
+type HealthChecker interface {
+	Healthy(ctx context.Context) error
+}
+
+// healthPollInterval is how often WaitHealthy re-checks an unhealthy component.
+const healthPollInterval = 250 * time.Millisecond
+
+// WaitHealthy polls every checker concurrently until all of them are healthy
+// or ctx is done. On timeout the error lists each component that was still
+// unhealthy together with its last failure.
+func WaitHealthy(ctx context.Context, checkers ...HealthChecker) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, c := range checkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				err := c.Healthy(ctx)
+				if err == nil {
+					return
+				}
+				select {
+				case <-ctx.Done():
+					mu.Lock()
+					errs = append(errs, fmt.Errorf("%T not healthy: %w", c, err))
+					mu.Unlock()
+					return
+				case <-time.After(healthPollInterval):
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
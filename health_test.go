@@ -0,0 +1,46 @@
+package testground_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dsvdev/testground"
+)
+
+// flaky becomes healthy after failing a number of checks.
+type flaky struct {
+	failures int
+}
+
+func (f *flaky) Healthy(ctx context.Context) error {
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("warming up")
+	}
+	return nil
+}
+
+func TestWaitHealthy(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := testground.WaitHealthy(ctx, &flaky{failures: 2}, &flaky{}); err != nil {
+		t.Errorf("WaitHealthy() error = %v", err)
+	}
+}
+
+func TestWaitHealthy_Timeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	err := testground.WaitHealthy(ctx, &flaky{}, &flaky{failures: 1000})
+	if err == nil {
+		t.Fatal("WaitHealthy() of a component that never gets healthy: expected error")
+	}
+	if !strings.Contains(err.Error(), "warming up") {
+		t.Errorf("error = %v, want the last failure of the unhealthy component", err)
+	}
+}
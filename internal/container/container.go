@@ -1,31 +1,121 @@
 package container
 
 import (
+	"archive/tar"
+	"bufio"
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"maps"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/docker/go-connections/nat"
 	"github.com/testcontainers/testcontainers-go"
+
+	"github.com/dsvdev/testground/internal/session"
+)
+
+// Labels put on containers started in reuse mode. LabelReuseHash identifies
+// the configuration the container was started with.
+const (
+	LabelReuse     = "testground.reuse"
+	LabelReuseHash = "testground.reuse.hash"
 )
 
 // Base holds the running testcontainers instance together with the resolved
 // host and mapped port. It is intended to be embedded as a named field in
 // service-specific Container types.
 type Base struct {
-	tc   testcontainers.Container
-	host string
-	port string
+	tc           testcontainers.Container
+	host         string
+	port         string
+	internalPort nat.Port
+
+	// reusable is set for containers started with WithReuse: Terminate leaves
+	// them running so that the next run can attach to them.
+	reusable bool
+	// reused is set when Start attached to a container left by an earlier run.
+	reused bool
+}
+
+type options struct {
+	reuseName string
+	reuseHash string
+}
+
+// Option configures Start.
+type Option func(*options)
+
+// WithReuse makes Start attach to a running container previously started with
+// the same name and configuration hash instead of creating a new one. The hash
+// should cover every setting that affects the container; see ConfigHash.
+func WithReuse(name, hash string) Option {
+	return func(o *options) {
+		o.reuseName = name
+		o.reuseHash = hash
+	}
+}
+
+// ConfigHash returns a short stable hash of v, suitable for WithReuse. v is
+// formatted with %+v, so it should be a plain struct or map of settings.
+func ConfigHash(v any) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%+v", v)))
+	return hex.EncodeToString(sum[:])[:12]
 }
 
 // Start launches a container from req, resolves the host and the mapped port
 // for internalPort, and returns a ready-to-use Base. On any error after the
 // container has been created, Terminate is called before returning.
-func Start(ctx context.Context, req testcontainers.ContainerRequest, internalPort nat.Port) (*Base, error) {
-	tc, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+func Start(ctx context.Context, req testcontainers.ContainerRequest, internalPort nat.Port, opts ...Option) (*Base, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	genericReq := testcontainers.GenericContainerRequest{
 		ContainerRequest: req,
 		Started:          true,
-	})
+	}
+	genericReq.Labels = withLabels(req.Labels, session.Labels())
+
+	var reused bool
+	if o.reuseName != "" {
+		genericReq.Name = fmt.Sprintf("testground-%s-%s", o.reuseName, o.reuseHash)
+		genericReq.Reuse = true
+		genericReq.Labels = withLabels(genericReq.Labels, map[string]string{
+			LabelReuse:     o.reuseName,
+			LabelReuseHash: o.reuseHash,
+		})
+
+		var err error
+		reused, err = exists(ctx, genericReq.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up reusable container: %w", err)
+		}
+	}
+
+	tc, err := testcontainers.GenericContainer(ctx, genericReq)
 	if err != nil {
+		// GenericContainer may return the container together with the error,
+		// e.g. when it exited before the wait strategy succeeded. Its output
+		// is the only clue to what went wrong, so include it.
+		if tc != nil {
+			logs := tail(context.WithoutCancel(ctx), tc, startupLogLines)
+			tc.Terminate(context.WithoutCancel(ctx))
+			if logs != "" {
+				return nil, fmt.Errorf("failed to start container: %w\nlast container logs:\n%s", err, logs)
+			}
+		}
 		return nil, fmt.Errorf("failed to start container: %w", err)
 	}
 
@@ -42,22 +132,312 @@ func Start(ctx context.Context, req testcontainers.ContainerRequest, internalPor
 	}
 
 	return &Base{
-		tc:   tc,
-		host: host,
-		port: mappedPort.Port(),
+		tc:           tc,
+		host:         host,
+		port:         mappedPort.Port(),
+		internalPort: internalPort,
+		reusable:     o.reuseName != "",
+		reused:       reused,
 	}, nil
 }
 
+// Create builds or pulls the image for req and creates the container without
+// starting it. Start it with Base.Start. This lets callers pay for the image
+// build before they stop a container the new one replaces.
+func Create(ctx context.Context, req testcontainers.ContainerRequest, internalPort nat.Port) (*Base, error) {
+	genericReq := testcontainers.GenericContainerRequest{ContainerRequest: req}
+	genericReq.Labels = withLabels(req.Labels, session.Labels())
+
+	tc, err := testcontainers.GenericContainer(ctx, genericReq)
+	if err != nil {
+		if tc != nil {
+			tc.Terminate(context.WithoutCancel(ctx))
+		}
+		return nil, fmt.Errorf("failed to create container: %w", err)
+	}
+
+	host, err := tc.Host(ctx)
+	if err != nil {
+		tc.Terminate(ctx)
+		return nil, fmt.Errorf("failed to get host: %w", err)
+	}
+	return &Base{tc: tc, host: host, internalPort: internalPort}, nil
+}
+
 // Host returns the host on which the container is reachable.
 func (b *Base) Host() string { return b.host }
 
 // Port returns the host-side mapped port as a string.
 func (b *Base) Port() string { return b.port }
 
-// Terminate stops and removes the container.
+// ID returns the Docker container ID.
+func (b *Base) ID() string { return b.tc.GetContainerID() }
+
+// Image returns the ID of the image the container runs, e.g. to start more
+// containers from an image built from a Dockerfile.
+func (b *Base) Image(ctx context.Context) (string, error) {
+	info, err := b.tc.Inspect(ctx)
+	if err != nil {
+		return "", fmt.Errorf("inspect container: %w", err)
+	}
+	return info.Image, nil
+}
+
+// MappedPort returns the host-side port mapped to an additional exposed
+// container port, for containers that expose more than one.
+func (b *Base) MappedPort(ctx context.Context, port nat.Port) (string, error) {
+	p, err := b.tc.MappedPort(ctx, port)
+	if err != nil {
+		return "", fmt.Errorf("failed to get mapped port %s: %w", port, err)
+	}
+	return p.Port(), nil
+}
+
+// Reused reports whether Start attached to a container left running by an
+// earlier run instead of creating a new one.
+func (b *Base) Reused() bool { return b.reused }
+
+// Logs returns the container's stdout and stderr produced so far. The caller
+// must close the returned reader.
+func (b *Base) Logs(ctx context.Context) (io.ReadCloser, error) {
+	return b.tc.Logs(ctx)
+}
+
+// Exec runs cmd inside the container and waits for it to finish. It returns
+// the exit code and the separated stdout and stderr. A non-zero exit code is
+// not an error.
+func (b *Base) Exec(ctx context.Context, cmd []string) (exitCode int, stdout, stderr string, err error) {
+	code, r, err := b.tc.Exec(ctx, cmd)
+	if err != nil {
+		return 0, "", "", fmt.Errorf("exec %q: %w", cmd, err)
+	}
+
+	var out, errOut bytes.Buffer
+	if _, err := stdcopy.StdCopy(&out, &errOut, r); err != nil {
+		return code, out.String(), errOut.String(), fmt.Errorf("exec %q: read output: %w", cmd, err)
+	}
+	return code, out.String(), errOut.String(), nil
+}
+
+// CopyFileTo copies the file at hostPath into the container at containerPath.
+func (b *Base) CopyFileTo(ctx context.Context, hostPath, containerPath string) error {
+	info, err := os.Stat(hostPath)
+	if err != nil {
+		return fmt.Errorf("copy %s to container: %w", hostPath, err)
+	}
+	if err := b.tc.CopyFileToContainer(ctx, hostPath, containerPath, int64(info.Mode().Perm())); err != nil {
+		return fmt.Errorf("copy %s to container: %w", hostPath, err)
+	}
+	return nil
+}
+
+// CopyFileFrom copies the file at containerPath out of the container to
+// hostPath, creating or truncating it.
+func (b *Base) CopyFileFrom(ctx context.Context, containerPath, hostPath string) error {
+	rc, err := b.tc.CopyFileFromContainer(ctx, containerPath)
+	if err != nil {
+		return fmt.Errorf("copy %s from container: %w", containerPath, err)
+	}
+	defer rc.Close()
+
+	f, err := os.Create(hostPath)
+	if err != nil {
+		return fmt.Errorf("copy %s from container: %w", containerPath, err)
+	}
+	if _, err := io.Copy(f, rc); err != nil {
+		f.Close()
+		return fmt.Errorf("copy %s from container: %w", containerPath, err)
+	}
+	return f.Close()
+}
+
+// CopyDirFrom copies the contents of the directory containerPath out of the
+// container into hostDir, which must exist. It works on stopped containers.
+func (b *Base) CopyDirFrom(ctx context.Context, containerPath, hostDir string) error {
+	cli, err := testcontainers.NewDockerClientWithOpts(ctx)
+	if err != nil {
+		return fmt.Errorf("copy %s from container: %w", containerPath, err)
+	}
+	defer cli.Close()
+
+	rc, _, err := cli.CopyFromContainer(ctx, b.ID(), containerPath)
+	if err != nil {
+		return fmt.Errorf("copy %s from container: %w", containerPath, err)
+	}
+	defer rc.Close()
+
+	// The archive holds the directory itself as the top-level entry.
+	tr := tar.NewReader(rc)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("copy %s from container: %w", containerPath, err)
+		}
+		_, rel, _ := strings.Cut(hdr.Name, "/")
+		if rel == "" || !filepath.IsLocal(rel) {
+			continue
+		}
+		target := filepath.Join(hostDir, rel)
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return fmt.Errorf("copy %s from container: %w", containerPath, err)
+			}
+		case tar.TypeReg:
+			if err := writeFile(target, tr); err != nil {
+				return fmt.Errorf("copy %s from container: %w", containerPath, err)
+			}
+		}
+	}
+}
+
+func writeFile(path string, r io.Reader) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Terminate stops and removes the container. Containers started with
+// WithReuse are left running for the next run.
 func (b *Base) Terminate(ctx context.Context) error {
-	if b.tc != nil {
+	if b.tc != nil && !b.reusable {
 		return b.tc.Terminate(ctx)
 	}
 	return nil
 }
+
+// Stop stops the container without removing it. The container keeps its
+// configuration, data and network aliases; Start brings it back. A nil
+// timeout uses the engine's default grace period before SIGKILL.
+func (b *Base) Stop(ctx context.Context, timeout *time.Duration) error {
+	if err := b.tc.Stop(ctx, timeout); err != nil {
+		return fmt.Errorf("stop container: %w", err)
+	}
+	return nil
+}
+
+// Start starts a container stopped with Stop, or created with Create, and
+// waits until its wait strategy succeeds. The mapped port is re-resolved,
+// since Docker only keeps host ports that were bound explicitly.
+func (b *Base) Start(ctx context.Context) error {
+	if err := b.tc.Start(ctx); err != nil {
+		if logs := tail(context.WithoutCancel(ctx), b.tc, startupLogLines); logs != "" {
+			return fmt.Errorf("start container: %w\nlast container logs:\n%s", err, logs)
+		}
+		return fmt.Errorf("start container: %w", err)
+	}
+	mappedPort, err := b.tc.MappedPort(ctx, b.internalPort)
+	if err != nil {
+		return fmt.Errorf("failed to get mapped port: %w", err)
+	}
+	b.port = mappedPort.Port()
+	return nil
+}
+
+// Restart stops and starts the container.
+func (b *Base) Restart(ctx context.Context, timeout *time.Duration) error {
+	if err := b.Stop(ctx, timeout); err != nil {
+		return err
+	}
+	return b.Start(ctx)
+}
+
+// Pause freezes every process in the container. Open connections stay
+// established but get no response until Unpause, which looks like a hung
+// dependency to its clients.
+func (b *Base) Pause(ctx context.Context) error {
+	cli, err := testcontainers.NewDockerClientWithOpts(ctx)
+	if err != nil {
+		return fmt.Errorf("pause container: %w", err)
+	}
+	defer cli.Close()
+	if err := cli.ContainerPause(ctx, b.ID()); err != nil {
+		return fmt.Errorf("pause container: %w", err)
+	}
+	return nil
+}
+
+// Unpause resumes a container frozen with Pause.
+func (b *Base) Unpause(ctx context.Context) error {
+	cli, err := testcontainers.NewDockerClientWithOpts(ctx)
+	if err != nil {
+		return fmt.Errorf("unpause container: %w", err)
+	}
+	defer cli.Close()
+	if err := cli.ContainerUnpause(ctx, b.ID()); err != nil {
+		return fmt.Errorf("unpause container: %w", err)
+	}
+	return nil
+}
+
+// FreePort asks the OS for an available TCP port and immediately releases it.
+// Binding a container port to it explicitly keeps the mapping stable across
+// Stop and Start. There is a small TOCTOU window, but in practice it is
+// negligible for tests.
+func FreePort() (int, error) {
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// startupLogLines is how many trailing log lines are attached to a start error.
+const startupLogLines = 50
+
+// tail returns the last n lines of the container's output, or an empty string
+// if the logs cannot be read.
+func tail(ctx context.Context, tc testcontainers.Container, n int) string {
+	rc, err := tc.Logs(ctx)
+	if err != nil {
+		return ""
+	}
+	defer rc.Close()
+
+	var lines []string
+	sc := bufio.NewScanner(rc)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+		if len(lines) > n {
+			lines = lines[1:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// exists reports whether a container with the given name exists, running or not.
+func exists(ctx context.Context, name string) (bool, error) {
+	cli, err := testcontainers.NewDockerClientWithOpts(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer cli.Close()
+
+	list, err := cli.ContainerList(ctx, dockercontainer.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("name", "^/"+name+"$")),
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(list) > 0, nil
+}
+
+func withLabels(base, extra map[string]string) map[string]string {
+	out := make(map[string]string, len(base)+len(extra))
+	maps.Copy(out, base)
+	maps.Copy(out, extra)
+	return out
+}
@@ -0,0 +1,107 @@
+package container
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+// LogLine is a line of container output with the time the Docker engine
+// received it.
+type LogLine struct {
+	Time time.Time
+	Text string
+}
+
+// EngineTime returns the current time of the Docker engine. Log timestamps
+// come from its clock, which may differ from the host's, e.g. in the VM of
+// Docker Desktop, so a point to pass to LogsSince is best taken from it.
+func EngineTime(ctx context.Context) (time.Time, error) {
+	cli, err := testcontainers.NewDockerClientWithOpts(ctx)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("docker engine time: %w", err)
+	}
+	defer cli.Close()
+	info, err := cli.Info(ctx)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("docker engine time: %w", err)
+	}
+	t, err := time.Parse(time.RFC3339Nano, info.SystemTime)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("docker engine time: %w", err)
+	}
+	return t, nil
+}
+
+// LogsSince returns the container's stdout and stderr produced since the
+// engine time since, without reading the earlier output. The caller must
+// close the returned reader.
+func (b *Base) LogsSince(ctx context.Context, since time.Time) (io.ReadCloser, error) {
+	data, err := b.logsSince(ctx, since, false)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// LogLinesSince returns the lines of output produced since the engine time
+// since, including lines received exactly at since, with their timestamps.
+func (b *Base) LogLinesSince(ctx context.Context, since time.Time) ([]LogLine, error) {
+	data, err := b.logsSince(ctx, since, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []LogLine
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		ts, text, ok := strings.Cut(sc.Text(), " ")
+		if !ok {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, ts)
+		if err != nil {
+			return nil, fmt.Errorf("read logs: timestamp %q: %w", ts, err)
+		}
+		lines = append(lines, LogLine{Time: t, Text: text})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read logs: %w", err)
+	}
+	return lines, nil
+}
+
+func (b *Base) logsSince(ctx context.Context, since time.Time, timestamps bool) ([]byte, error) {
+	cli, err := testcontainers.NewDockerClientWithOpts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("read logs: %w", err)
+	}
+	defer cli.Close()
+
+	opts := dockercontainer.LogsOptions{ShowStdout: true, ShowStderr: true, Timestamps: timestamps}
+	if !since.IsZero() {
+		opts.Since = fmt.Sprintf("%d.%09d", since.Unix(), since.Nanosecond())
+	}
+	rc, err := cli.ContainerLogs(ctx, b.ID(), opts)
+	if err != nil {
+		return nil, fmt.Errorf("read logs: %w", err)
+	}
+	defer rc.Close()
+
+	// Containers run without a TTY, so stdout and stderr are multiplexed.
+	// Both go to one buffer to keep their order.
+	var buf bytes.Buffer
+	if _, err := stdcopy.StdCopy(&buf, &buf, rc); err != nil {
+		return nil, fmt.Errorf("read logs: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,126 @@
+// Package session identifies the current test binary run. Every container and
+// network started by testground is labeled with the session ID, and a
+// heartbeat file is touched periodically while the process is alive, so that
+// testground.Reap can tell abandoned resources from live ones.
+package session
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Labels put on every resource started by testground.
+const (
+	Label        = "testground"
+	LabelSession = "testground.session"
+)
+
+// HeartbeatInterval is how often the heartbeat file of a live session is
+// touched. Reap must be called with an olderThan well above this value.
+const HeartbeatInterval = 10 * time.Second
+
+var (
+	id       = newID()
+	beatOnce sync.Once
+	beatStop = make(chan struct{})
+	stopOnce sync.Once
+)
+
+// ID returns the session ID of the current process.
+func ID() string { return id }
+
+// Labels returns the labels to put on a new resource. The first call starts
+// the heartbeat of the current session.
+func Labels() map[string]string {
+	beatOnce.Do(startHeartbeat)
+	return map[string]string{
+		Label:        "true",
+		LabelSession: id,
+	}
+}
+
+// Dir returns the directory holding the heartbeat files of all sessions.
+func Dir() string {
+	return filepath.Join(os.TempDir(), "testground", "sessions")
+}
+
+// HeartbeatPath returns the heartbeat file of the given session.
+func HeartbeatPath(sessionID string) string {
+	return filepath.Join(Dir(), sessionID)
+}
+
+// LastHeartbeat returns the time the session's heartbeat file was last
+// touched, and false if there is no heartbeat file for it on this host.
+func LastHeartbeat(sessionID string) (time.Time, bool) {
+	info, err := os.Stat(HeartbeatPath(sessionID))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return info.ModTime(), true
+}
+
+// StopHeartbeat stops the heartbeat of the current session and removes its
+// file. It is meant for a clean exit, once every resource of the session has
+// been removed; the heartbeat does not start again afterwards.
+func StopHeartbeat() {
+	stopOnce.Do(func() {
+		beatOnce.Do(func() {}) // a heartbeat that never started stays off
+		close(beatStop)
+		os.Remove(HeartbeatPath(id)) //nolint:errcheck
+	})
+}
+
+// StaleHeartbeats returns the sessions other than the current one whose
+// heartbeat file was last touched before cutoff.
+func StaleHeartbeats(cutoff time.Time) []string {
+	entries, err := os.ReadDir(Dir())
+	if err != nil {
+		return nil
+	}
+	var stale []string
+	for _, e := range entries {
+		if e.IsDir() || e.Name() == id {
+			continue
+		}
+		if beat, ok := LastHeartbeat(e.Name()); ok && beat.Before(cutoff) {
+			stale = append(stale, e.Name())
+		}
+	}
+	return stale
+}
+
+func startHeartbeat() {
+	path := HeartbeatPath(id)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return // liveness falls back to resource age in Reap
+	}
+	if err := os.WriteFile(path, []byte(fmt.Sprintf("%d\n", os.Getpid())), 0o644); err != nil {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(HeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				os.Chtimes(path, now, now) //nolint:errcheck
+			case <-beatStop:
+				return
+			}
+		}
+	}()
+}
+
+func newID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("session: crypto/rand failed: %v", err))
+	}
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,46 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestStaleHeartbeats(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	if err := os.MkdirAll(Dir(), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	for _, name := range []string{"old", "fresh", id} {
+		path := filepath.Join(Dir(), name)
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if name != "fresh" {
+			if err := os.Chtimes(path, old, old); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	got := StaleHeartbeats(time.Now().Add(-time.Minute))
+	if !slices.Equal(got, []string{"old"}) {
+		t.Errorf("StaleHeartbeats() = %v, want [old]", got)
+	}
+}
+
+func TestStopHeartbeat(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	Labels()
+	if _, ok := LastHeartbeat(id); !ok {
+		t.Fatal("no heartbeat file after Labels")
+	}
+
+	StopHeartbeat()
+	if _, ok := LastHeartbeat(id); ok {
+		t.Error("heartbeat file still exists after StopHeartbeat")
+	}
+}
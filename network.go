@@ -3,20 +3,67 @@ package testground
 import (
 	"context"
 	"fmt"
+	"sync"
+	"testing"
 
+	"github.com/docker/docker/api/types/filters"
+	dockernetwork "github.com/docker/docker/api/types/network"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/network"
+
+	"github.com/dsvdev/testground/internal/container"
+	"github.com/dsvdev/testground/internal/session"
 )
 
 type Network struct {
 	network *testcontainers.DockerNetwork
 	name    string
+
+	mu sync.Mutex
+	// detached holds the aliases of containers removed by Disconnect, so that
+	// Reconnect can restore them.
+	detached map[string][]string
+}
+
+// Member is a container attached to a Network. All container types in this
+// module implement it.
+type Member interface {
+	ContainerID() string
+}
+
+type networkConfig struct {
+	reusableName string
+}
+
+// NetworkOption configures NewNetwork.
+type NetworkOption func(*networkConfig)
+
+// WithReusableName creates the network under a fixed name, or attaches to an
+// existing network with that name. A reusable network is left in place by
+// Terminate so that containers started in reuse mode keep their network
+// across test runs.
+func WithReusableName(name string) NetworkOption {
+	return func(c *networkConfig) {
+		c.reusableName = name
+	}
 }
 
-func NewNetwork(ctx context.Context) (*Network, error) {
+func NewNetwork(ctx context.Context, opts ...NetworkOption) (*Network, error) {
+	var cfg networkConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.reusableName != "" {
+		if err := ensureNetwork(ctx, cfg.reusableName); err != nil {
+			return nil, fmt.Errorf("failed to create network: %w", err)
+		}
+		return &Network{name: cfg.reusableName}, nil
+	}
+
 	net, err := network.New(ctx,
 		network.WithDriver("bridge"),
-		network.WithLabels(map[string]string{"testground": "true"}),
+		network.WithLabels(session.Labels()),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create network: %w", err)
@@ -37,4 +84,124 @@ func (n *Network) Terminate(ctx context.Context) error {
 		return n.network.Remove(ctx)
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+// Disconnect detaches c from the network, simulating a network partition:
+// other containers can no longer resolve or reach it, while it keeps running.
+// Connections through host ports are not affected. Reconnect restores the
+// container with its original aliases; Partition does so automatically at the
+// end of the test.
+func (n *Network) Disconnect(ctx context.Context, c Member) error {
+	cli, err := testcontainers.NewDockerClientWithOpts(ctx)
+	if err != nil {
+		return fmt.Errorf("disconnect: %w", err)
+	}
+	defer cli.Close()
+
+	id := c.ContainerID()
+	info, err := cli.ContainerInspect(ctx, id)
+	if err != nil {
+		return fmt.Errorf("disconnect %s: %w", shortID(id), err)
+	}
+	endpoint, ok := info.NetworkSettings.Networks[n.name]
+	if !ok {
+		return fmt.Errorf("disconnect %s: container is not attached to network %s", shortID(id), n.name)
+	}
+
+	if err := cli.NetworkDisconnect(ctx, n.name, id, false); err != nil {
+		return fmt.Errorf("disconnect %s: %w", shortID(id), err)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.detached == nil {
+		n.detached = make(map[string][]string)
+	}
+	n.detached[id] = endpoint.Aliases
+	return nil
+}
+
+// Partition disconnects c from the network for the rest of the test, like
+// Disconnect, and reconnects it in t.Cleanup unless Reconnect was called
+// before. A test that fails mid-partition thus leaves no container cut off.
+func (n *Network) Partition(t *testing.T, c Member) {
+	t.Helper()
+	ctx := context.Background()
+	if err := n.Disconnect(ctx, c); err != nil {
+		t.Fatalf("testground: %v", err)
+	}
+	id := c.ContainerID()
+	t.Cleanup(func() {
+		n.mu.Lock()
+		_, detached := n.detached[id]
+		n.mu.Unlock()
+		if !detached {
+			return
+		}
+		if err := n.Reconnect(ctx, c); err != nil {
+			t.Logf("warning: testground: %v", err)
+		}
+	})
+}
+
+// Reconnect attaches a container removed by Disconnect back to the network
+// under the aliases it had before.
+func (n *Network) Reconnect(ctx context.Context, c Member) error {
+	id := c.ContainerID()
+	n.mu.Lock()
+	aliases, ok := n.detached[id]
+	n.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("reconnect %s: container was not disconnected from network %s", shortID(id), n.name)
+	}
+
+	cli, err := testcontainers.NewDockerClientWithOpts(ctx)
+	if err != nil {
+		return fmt.Errorf("reconnect: %w", err)
+	}
+	defer cli.Close()
+
+	if err := cli.NetworkConnect(ctx, n.name, id, &dockernetwork.EndpointSettings{Aliases: aliases}); err != nil {
+		return fmt.Errorf("reconnect %s: %w", shortID(id), err)
+	}
+
+	n.mu.Lock()
+	delete(n.detached, id)
+	n.mu.Unlock()
+	return nil
+}
+
+// ensureNetwork creates a bridge network with the given name unless it
+// already exists. The network carries no testcontainers session labels, so
+// the session reaper leaves it alone.
+func ensureNetwork(ctx context.Context, name string) error {
+	cli, err := testcontainers.NewDockerClientWithOpts(ctx)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	list, err := cli.NetworkList(ctx, dockernetwork.ListOptions{
+		Filters: filters.NewArgs(filters.Arg("name", "^"+name+"$")),
+	})
+	if err != nil {
+		return err
+	}
+	if len(list) > 0 {
+		return nil
+	}
+
+	_, err = cli.NetworkCreate(ctx, name, dockernetwork.CreateOptions{
+		Driver: "bridge",
+		Labels: map[string]string{session.Label: "true", container.LabelReuse: name},
+	})
+	return err
+}
+
+// shortID returns the short form of a container ID used in Docker's output.
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
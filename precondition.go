@@ -2,19 +2,230 @@ package testground
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
 	"testing"
+	"time"
 )
 
 type Precondition interface {
 	Apply(ctx context.Context, t *testing.T) error
 }
 
+// PreconditionFunc adapts an ordinary function to the Precondition interface.
+type PreconditionFunc func(ctx context.Context, t *testing.T) error
+
+func (f PreconditionFunc) Apply(ctx context.Context, t *testing.T) error {
+	return f(ctx, t)
+}
+
+// Reverter is a Precondition that knows how to undo its own effect, e.g.
+// delete the rows it inserted or the topic it created.
+type Reverter interface {
+	Precondition
+	Revert(ctx context.Context, t *testing.T) error
+}
+
 func Apply(t *testing.T, preconditions ...Precondition) {
 	t.Helper()
 	ctx := context.Background()
 	for _, p := range preconditions {
-		if err := p.Apply(ctx, t); err != nil {
+		if err := apply(ctx, t, p); err != nil {
 			t.Fatalf("precondition failed: %v", err)
 		}
 	}
 }
+
+// combinator is implemented by the preconditions in this file that apply
+// other preconditions. Instead of registering the Reverters they applied,
+// they return them, so that only the outermost precondition registers them
+// and each exactly once, however often a Retry attempted it.
+type combinator interface {
+	applySteps(ctx context.Context, t *testing.T) ([]Reverter, error)
+}
+
+// apply runs p and registers the Revert of every Reverter it applied with
+// t.Cleanup, also when p failed half-way. Cleanup functions run in LIFO
+// order, so reverts happen in reverse order of application. A failed revert
+// is reported as a warning: by the time cleanup runs the target container
+// may already be terminated.
+func apply(ctx context.Context, t *testing.T, p Precondition) error {
+	reverters, err := collect(ctx, t, p)
+	for _, r := range reverters {
+		t.Cleanup(func() {
+			if err := r.Revert(context.Background(), t); err != nil {
+				t.Logf("warning: failed to revert precondition: %v", err)
+			}
+		})
+	}
+	return err
+}
+
+// collect runs p and returns the Reverters it applied, without registering
+// them.
+func collect(ctx context.Context, t *testing.T, p Precondition) ([]Reverter, error) {
+	if c, ok := p.(combinator); ok {
+		return c.applySteps(ctx, t)
+	}
+	if err := p.Apply(ctx, t); err != nil {
+		return nil, err
+	}
+	if r, ok := p.(Reverter); ok {
+		return []Reverter{r}, nil
+	}
+	return nil, nil
+}
+
+// revertNow reverts reverters in reverse order, logging failures.
+func revertNow(ctx context.Context, t *testing.T, reverters []Reverter) {
+	for _, r := range slices.Backward(reverters) {
+		if err := r.Revert(ctx, t); err != nil {
+			t.Logf("warning: failed to revert precondition: %v", err)
+		}
+	}
+}
+
+// ── Sequence ─────────────────────────────────────────────────────────────────
+
+type sequencePrecondition struct {
+	steps []Precondition
+}
+
+// Sequence returns a Precondition that applies steps one after another and
+// stops at the first failure. It is useful for grouping dependent steps into
+// a single unit that can be passed to Parallel, Retry or When.
+//
+// Like every combinator in this file, Sequence registers the Revert of each
+// successfully applied Reverter step with t.Cleanup, once the outermost
+// combinator has been applied.
+func Sequence(steps ...Precondition) Precondition {
+	return &sequencePrecondition{steps: steps}
+}
+
+func (p *sequencePrecondition) Apply(ctx context.Context, t *testing.T) error {
+	return apply(ctx, t, p)
+}
+
+func (p *sequencePrecondition) applySteps(ctx context.Context, t *testing.T) ([]Reverter, error) {
+	var applied []Reverter
+	for i, step := range p.steps {
+		reverters, err := collect(ctx, t, step)
+		applied = append(applied, reverters...)
+		if err != nil {
+			return applied, fmt.Errorf("sequence step %d: %w", i, err)
+		}
+	}
+	return applied, nil
+}
+
+// ── Parallel ─────────────────────────────────────────────────────────────────
+
+type parallelPrecondition struct {
+	steps []Precondition
+}
+
+// Parallel returns a Precondition that applies all steps concurrently and
+// waits for every one of them to finish. Unlike Sequence it does not stop at
+// the first failure: all errors are collected and returned joined together.
+// Steps must be independent of each other and must not call t.Fatal.
+func Parallel(steps ...Precondition) Precondition {
+	return &parallelPrecondition{steps: steps}
+}
+
+func (p *parallelPrecondition) Apply(ctx context.Context, t *testing.T) error {
+	return apply(ctx, t, p)
+}
+
+func (p *parallelPrecondition) applySteps(ctx context.Context, t *testing.T) ([]Reverter, error) {
+	errs := make([]error, len(p.steps))
+	reverters := make([][]Reverter, len(p.steps))
+
+	var wg sync.WaitGroup
+	for i, step := range p.steps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			if reverters[i], err = collect(ctx, t, step); err != nil {
+				errs[i] = fmt.Errorf("parallel step %d: %w", i, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return slices.Concat(reverters...), errors.Join(errs...)
+}
+
+// ── Retry ────────────────────────────────────────────────────────────────────
+
+type retryPrecondition struct {
+	step     Precondition
+	attempts int
+	backoff  time.Duration
+}
+
+// Retry returns a Precondition that applies step up to attempts times, waiting
+// backoff between consecutive attempts. It returns nil on the first success
+// and the last error once all attempts are exhausted. Waiting is interrupted
+// when ctx is done. An attempts value below 1 is treated as 1.
+//
+// The Reverters a failed attempt applied, e.g. the first steps of a
+// Sequence, are reverted before the next attempt; only those of the
+// successful attempt are registered with t.Cleanup.
+func Retry(step Precondition, attempts int, backoff time.Duration) Precondition {
+	if attempts < 1 {
+		attempts = 1
+	}
+	return &retryPrecondition{step: step, attempts: attempts, backoff: backoff}
+}
+
+func (p *retryPrecondition) Apply(ctx context.Context, t *testing.T) error {
+	return apply(ctx, t, p)
+}
+
+func (p *retryPrecondition) applySteps(ctx context.Context, t *testing.T) ([]Reverter, error) {
+	var err error
+	for i := range p.attempts {
+		if i > 0 {
+			timer := time.NewTimer(p.backoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, fmt.Errorf("retry: %w (last error: %v)", ctx.Err(), err)
+			case <-timer.C:
+			}
+		}
+		var reverters []Reverter
+		if reverters, err = collect(ctx, t, p.step); err == nil {
+			return reverters, nil
+		}
+		revertNow(context.WithoutCancel(ctx), t, reverters)
+	}
+	return nil, fmt.Errorf("retry: giving up after %d attempt(s): %w", p.attempts, err)
+}
+
+// ── When ─────────────────────────────────────────────────────────────────────
+
+type whenPrecondition struct {
+	cond bool
+	step Precondition
+}
+
+// When returns a Precondition that applies step only if cond is true and is a
+// no-op otherwise.
+func When(cond bool, step Precondition) Precondition {
+	return &whenPrecondition{cond: cond, step: step}
+}
+
+func (p *whenPrecondition) Apply(ctx context.Context, t *testing.T) error {
+	return apply(ctx, t, p)
+}
+
+func (p *whenPrecondition) applySteps(ctx context.Context, t *testing.T) ([]Reverter, error) {
+	if !p.cond {
+		return nil, nil
+	}
+	return collect(ctx, t, p.step)
+}
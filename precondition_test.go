@@ -0,0 +1,253 @@
+package testground_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dsvdev/testground"
+)
+
+func record(mu *sync.Mutex, order *[]string, name string) testground.Precondition {
+	return testground.PreconditionFunc(func(ctx context.Context, t *testing.T) error {
+		mu.Lock()
+		defer mu.Unlock()
+		*order = append(*order, name)
+		return nil
+	})
+}
+
+func failing(err error) testground.Precondition {
+	return testground.PreconditionFunc(func(ctx context.Context, t *testing.T) error {
+		return err
+	})
+}
+
+func TestSequence_StopsAtFirstFailure(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	errBoom := errors.New("boom")
+
+	err := testground.Sequence(
+		record(&mu, &order, "a"),
+		failing(errBoom),
+		record(&mu, &order, "c"),
+	).Apply(context.Background(), t)
+
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("Sequence() error = %v, want %v", err, errBoom)
+	}
+	if len(order) != 1 || order[0] != "a" {
+		t.Errorf("applied steps = %v, want [a]", order)
+	}
+}
+
+func TestParallel_RunsConcurrently(t *testing.T) {
+	const n = 5
+	var running, peak atomic.Int32
+	release := make(chan struct{})
+
+	steps := make([]testground.Precondition, n)
+	for i := range steps {
+		steps[i] = testground.PreconditionFunc(func(ctx context.Context, t *testing.T) error {
+			cur := running.Add(1)
+			for {
+				old := peak.Load()
+				if cur <= old || peak.CompareAndSwap(old, cur) {
+					break
+				}
+			}
+			<-release
+			running.Add(-1)
+			return nil
+		})
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- testground.Parallel(steps...).Apply(context.Background(), t) }()
+
+	deadline := time.After(5 * time.Second)
+	for peak.Load() < n {
+		select {
+		case <-deadline:
+			t.Fatalf("peak concurrency = %d, want %d", peak.Load(), n)
+		case <-time.After(time.Millisecond):
+		}
+	}
+	close(release)
+
+	if err := <-done; err != nil {
+		t.Fatalf("Parallel() error = %v", err)
+	}
+}
+
+func TestParallel_CollectsAllErrors(t *testing.T) {
+	errA := errors.New("a failed")
+	errB := errors.New("b failed")
+
+	err := testground.Parallel(
+		failing(errA),
+		failing(nil),
+		failing(errB),
+	).Apply(context.Background(), t)
+
+	if !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Fatalf("Parallel() error = %v, want both %v and %v", err, errA, errB)
+	}
+}
+
+func TestRetry_SucceedsEventually(t *testing.T) {
+	var calls int
+	p := testground.PreconditionFunc(func(ctx context.Context, t *testing.T) error {
+		calls++
+		if calls < 3 {
+			return errors.New("not ready")
+		}
+		return nil
+	})
+
+	if err := testground.Retry(p, 5, time.Millisecond).Apply(context.Background(), t); err != nil {
+		t.Fatalf("Retry() error = %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+}
+
+func TestRetry_GivesUp(t *testing.T) {
+	errNotReady := errors.New("not ready")
+	var calls int
+	p := testground.PreconditionFunc(func(ctx context.Context, t *testing.T) error {
+		calls++
+		return errNotReady
+	})
+
+	err := testground.Retry(p, 3, time.Millisecond).Apply(context.Background(), t)
+	if !errors.Is(err, errNotReady) {
+		t.Fatalf("Retry() error = %v, want %v", err, errNotReady)
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+}
+
+func TestRetry_StopsOnContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls int
+	p := testground.PreconditionFunc(func(ctx context.Context, t *testing.T) error {
+		calls++
+		cancel()
+		return errors.New("not ready")
+	})
+
+	err := testground.Retry(p, 10, time.Hour).Apply(ctx, t)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Retry() error = %v, want %v", err, context.Canceled)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestWhen(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+
+	testground.Apply(t,
+		testground.When(true, record(&mu, &order, "yes")),
+		testground.When(false, record(&mu, &order, "no")),
+	)
+
+	if len(order) != 1 || order[0] != "yes" {
+		t.Errorf("applied steps = %v, want [yes]", order)
+	}
+}
+
+type reverter struct {
+	name  string
+	mu    *sync.Mutex
+	order *[]string
+}
+
+func (r *reverter) Apply(ctx context.Context, t *testing.T) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	*r.order = append(*r.order, "apply "+r.name)
+	return nil
+}
+
+func (r *reverter) Revert(ctx context.Context, t *testing.T) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	*r.order = append(*r.order, "revert "+r.name)
+	return nil
+}
+
+func TestApply_RevertsInReverseOrder(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	newReverter := func(name string) *reverter {
+		return &reverter{name: name, mu: &mu, order: &order}
+	}
+
+	t.Run("inner", func(t *testing.T) {
+		testground.Apply(t,
+			newReverter("a"),
+			record(&mu, &order, "plain"),
+			testground.Sequence(newReverter("b"), newReverter("c")),
+		)
+	})
+
+	expected := []string{
+		"apply a", "plain", "apply b", "apply c",
+		"revert c", "revert b", "revert a",
+	}
+	if len(order) != len(expected) {
+		t.Fatalf("got %d events, want %d: %v", len(order), len(expected), order)
+	}
+	for i, event := range expected {
+		if order[i] != event {
+			t.Errorf("event[%d] = %q, want %q", i, order[i], event)
+		}
+	}
+}
+
+func TestRetry_RevertsFailedAttemptOnce(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	failures := 1
+	flaky := testground.PreconditionFunc(func(ctx context.Context, t *testing.T) error {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, "flaky")
+		if failures > 0 {
+			failures--
+			return errors.New("not yet")
+		}
+		return nil
+	})
+
+	t.Run("inner", func(t *testing.T) {
+		testground.Apply(t, testground.Retry(
+			testground.Sequence(&reverter{name: "a", mu: &mu, order: &order}, flaky),
+			2, time.Millisecond,
+		))
+	})
+
+	expected := []string{
+		"apply a", "flaky", "revert a",
+		"apply a", "flaky",
+		"revert a",
+	}
+	if len(order) != len(expected) {
+		t.Fatalf("got %d events, want %d: %v", len(order), len(expected), order)
+	}
+	for i, event := range expected {
+		if order[i] != event {
+			t.Errorf("event[%d] = %q, want %q", i, order[i], event)
+		}
+	}
+}
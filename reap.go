@@ -0,0 +1,117 @@
+package testground
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	dockernetwork "github.com/docker/docker/api/types/network"
+	"github.com/testcontainers/testcontainers-go"
+
+	"github.com/dsvdev/testground/internal/container"
+	"github.com/dsvdev/testground/internal/session"
+)
+
+// ReapResult lists the resources removed by Reap.
+type ReapResult struct {
+	Containers []string
+	Networks   []string
+}
+
+// Reap removes containers and networks left behind by test binaries that
+// crashed or were killed before their cleanup ran. A resource is considered
+// abandoned when it belongs to a session other than the current one, that
+// session has not touched its heartbeat file for olderThan, and the resource
+// itself was created more than olderThan ago. Resources of sessions without a
+// heartbeat file on this host, e.g. started from another machine against the
+// same Docker daemon, are judged by age alone. Heartbeat files untouched for
+// olderThan are deleted once their session's resources are removed.
+//
+// Containers and networks started in reuse mode are never reaped.
+// olderThan should be well above session.HeartbeatInterval (10s); an hour is
+// a reasonable default for CI runners.
+func Reap(ctx context.Context, olderThan time.Duration) (*ReapResult, error) {
+	cli, err := testcontainers.NewDockerClientWithOpts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("reap: connect to docker: %w", err)
+	}
+	defer cli.Close()
+
+	cutoff := time.Now().Add(-olderThan)
+	stale := func(labels map[string]string, created time.Time) bool {
+		if _, ok := labels[container.LabelReuse]; ok {
+			return false
+		}
+		sessionID := labels[session.LabelSession]
+		if sessionID == session.ID() {
+			return false
+		}
+		if beat, ok := session.LastHeartbeat(sessionID); ok && beat.After(cutoff) {
+			return false
+		}
+		return created.Before(cutoff)
+	}
+
+	labelFilter := filters.NewArgs(filters.Arg("label", session.Label+"=true"))
+	result := &ReapResult{}
+	var errs []error
+
+	containers, err := cli.ContainerList(ctx, dockercontainer.ListOptions{All: true, Filters: labelFilter})
+	if err != nil {
+		return nil, fmt.Errorf("reap: list containers: %w", err)
+	}
+	// sessions holds the sessions whose resources were removed, failed
+	// those of which some could not be.
+	sessions := make(map[string]bool)
+	failed := make(map[string]bool)
+	for _, c := range containers {
+		if !stale(c.Labels, time.Unix(c.Created, 0)) {
+			continue
+		}
+		if err := cli.ContainerRemove(ctx, c.ID, dockercontainer.RemoveOptions{Force: true, RemoveVolumes: true}); err != nil {
+			errs = append(errs, fmt.Errorf("remove container %s: %w", shortID(c.ID), err))
+			failed[c.Labels[session.LabelSession]] = true
+			continue
+		}
+		result.Containers = append(result.Containers, shortID(c.ID))
+		sessions[c.Labels[session.LabelSession]] = true
+	}
+
+	networks, err := cli.NetworkList(ctx, dockernetwork.ListOptions{Filters: labelFilter})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("list networks: %w", err))
+	}
+	for _, n := range networks {
+		if !stale(n.Labels, n.Created) {
+			continue
+		}
+		if err := cli.NetworkRemove(ctx, n.ID); err != nil {
+			errs = append(errs, fmt.Errorf("remove network %s: %w", n.Name, err))
+			failed[n.Labels[session.LabelSession]] = true
+			continue
+		}
+		result.Networks = append(result.Networks, n.Name)
+		sessions[n.Labels[session.LabelSession]] = true
+	}
+
+	// Heartbeat files outlive sessions that exited cleanly, which leave no
+	// resources behind. Those of sessions whose resources could not all be
+	// removed are kept for the next Reap.
+	for _, id := range session.StaleHeartbeats(cutoff) {
+		sessions[id] = true
+	}
+	for id := range sessions {
+		if id != "" && !failed[id] {
+			os.Remove(session.HeartbeatPath(id)) //nolint:errcheck
+		}
+	}
+
+	if len(errs) > 0 {
+		return result, fmt.Errorf("reap: %w", errors.Join(errs...))
+	}
+	return result, nil
+}
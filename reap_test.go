@@ -0,0 +1,29 @@
+package testground_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dsvdev/testground"
+)
+
+func TestReap_KeepsCurrentSession(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	net, err := testground.NewNetwork(ctx)
+	if err != nil {
+		t.Fatalf("NewNetwork() error = %v", err)
+	}
+	t.Cleanup(func() { net.Terminate(context.Background()) })
+
+	res, err := testground.Reap(ctx, 0)
+	if err != nil {
+		t.Fatalf("Reap() error = %v", err)
+	}
+	if slices.Contains(res.Networks, net.Name()) {
+		t.Errorf("Reap() removed network %s of the current session", net.Name())
+	}
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+
+	"github.com/testcontainers/testcontainers-go"
+)
+
+// binaryPath is where WithGoPackage puts the compiled service in the container.
+const binaryPath = "/service"
+
+// buildBinary builds cfg.goPackage for the architecture set with WithGoArch,
+// or else for the Docker engine's, with the flags that cfg requires.
+func buildBinary(ctx context.Context, cfg config) (string, error) {
+	target := goTarget{arch: cfg.goArch}
+	if target.arch == "" {
+		var err error
+		if target, err = engineGoTarget(ctx); err != nil {
+			return "", fmt.Errorf("service: %w", err)
+		}
+	}
+	var flags []string
+	if cfg.coverProfile != "" {
+		flags = append(flags, "-cover")
+	}
+	return buildGoBinary(ctx, cfg.goPackage, target, flags)
+}
+
+// goTarget is the architecture a binary is compiled for: a GOARCH and, for
+// 32-bit ARM, the GOARM version if it is known.
+type goTarget struct {
+	arch string
+	arm  string
+}
+
+// buildGoBinary cross-compiles pkg for Linux on target and returns the path
+// of the static binary inside a new temporary directory. The caller removes
+// the directory. The Go build cache makes repeated builds incremental.
+func buildGoBinary(ctx context.Context, pkg string, target goTarget, flags []string) (string, error) {
+	dir, err := os.MkdirTemp("", "testground-service-")
+	if err != nil {
+		return "", fmt.Errorf("build %s: %w", pkg, err)
+	}
+	out := filepath.Join(dir, "service")
+
+	args := append([]string{"build", "-o", out}, flags...)
+	args = append(args, pkg)
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Env = append(os.Environ(), "GOOS=linux", "GOARCH="+target.arch, "CGO_ENABLED=0")
+	if target.arm != "" {
+		cmd.Env = append(cmd.Env, "GOARM="+target.arm)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("build %s: %w\n%s", pkg, err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return out, nil
+}
+
+// engineTarget caches the target of the Docker engine, which does not change
+// while the tests run.
+var engineTarget struct {
+	mu     sync.Mutex
+	target goTarget
+}
+
+// engineGoTarget returns the target matching the architecture of the Docker
+// engine. It may differ from the host's, e.g. with a remote DOCKER_HOST.
+func engineGoTarget(ctx context.Context) (goTarget, error) {
+	engineTarget.mu.Lock()
+	defer engineTarget.mu.Unlock()
+	if engineTarget.target.arch != "" {
+		return engineTarget.target, nil
+	}
+
+	cli, err := testcontainers.NewDockerClientWithOpts(ctx)
+	if err != nil {
+		return goTarget{}, fmt.Errorf("docker engine architecture: %w", err)
+	}
+	defer cli.Close()
+	info, err := cli.Info(ctx)
+	if err != nil {
+		return goTarget{}, fmt.Errorf("docker engine architecture: %w", err)
+	}
+	target, ok := goArch(info.Architecture)
+	if !ok {
+		return goTarget{}, fmt.Errorf("docker engine architecture %q has no GOARCH, set one with WithGoArch", info.Architecture)
+	}
+	engineTarget.target = target
+	return target, nil
+}
+
+// goArch maps an architecture as reported by the Docker engine, which uses
+// the names of uname -m, to a target. GOARM is set for the ARM versions whose
+// name gives it away, since Go's default of 7 does not run on ARMv6.
+func goArch(machine string) (goTarget, bool) {
+	switch machine {
+	case "x86_64", "amd64":
+		return goTarget{arch: "amd64"}, true
+	case "aarch64", "arm64":
+		return goTarget{arch: "arm64"}, true
+	case "armv7l":
+		return goTarget{arch: "arm", arm: "7"}, true
+	case "armv6l":
+		return goTarget{arch: "arm", arm: "6"}, true
+	case "arm":
+		return goTarget{arch: "arm"}, true
+	case "i386", "i686", "386":
+		return goTarget{arch: "386"}, true
+	case "ppc64le", "s390x", "riscv64":
+		return goTarget{arch: machine}, true
+	}
+	return goTarget{}, false
+}
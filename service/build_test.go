@@ -0,0 +1,94 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"debug/buildinfo"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestBuildGoBinary(t *testing.T) {
+	bin, err := buildGoBinary(context.Background(), "../example/simple_backend/cmd", goTarget{arch: runtime.GOARCH}, nil)
+	if err != nil {
+		t.Fatalf("buildGoBinary() error = %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(filepath.Dir(bin)) })
+
+	data, err := os.ReadFile(bin)
+	if err != nil {
+		t.Fatalf("read binary: %v", err)
+	}
+	if !bytes.HasPrefix(data, []byte("\x7fELF")) {
+		t.Error("binary is not a Linux ELF executable")
+	}
+}
+
+func TestBuildGoBinary_ReportsCompilerOutput(t *testing.T) {
+	_, err := buildGoBinary(context.Background(), "./does-not-exist", goTarget{arch: runtime.GOARCH}, nil)
+	if err == nil {
+		t.Fatal("buildGoBinary() of a missing package: expected error")
+	}
+	if !strings.Contains(err.Error(), "does-not-exist") {
+		t.Errorf("error = %v, want the go build output", err)
+	}
+}
+
+func TestBuildBinary_EngineArchitecture(t *testing.T) {
+	// Pretend the Docker engine runs on ARMv6, which needs both GOARCH and
+	// GOARM to differ from the host's.
+	engineTarget.mu.Lock()
+	saved := engineTarget.target
+	engineTarget.target = goTarget{arch: "arm", arm: "6"}
+	engineTarget.mu.Unlock()
+	t.Cleanup(func() {
+		engineTarget.mu.Lock()
+		engineTarget.target = saved
+		engineTarget.mu.Unlock()
+	})
+
+	cfg := defaultConfig()
+	cfg.goPackage = "../example/simple_backend/cmd"
+	bin, err := buildBinary(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("buildBinary() error = %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(filepath.Dir(bin)) })
+
+	info, err := buildinfo.ReadFile(bin)
+	if err != nil {
+		t.Fatalf("read build info: %v", err)
+	}
+	settings := make(map[string]string)
+	for _, s := range info.Settings {
+		settings[s.Key] = s.Value
+	}
+	if settings["GOARCH"] != "arm" || settings["GOARM"] != "6" {
+		t.Errorf("built for GOARCH=%q GOARM=%q, want arm and 6", settings["GOARCH"], settings["GOARM"])
+	}
+}
+
+func TestGoArch(t *testing.T) {
+	tests := []struct {
+		machine string
+		want    goTarget
+		ok      bool
+	}{
+		{machine: "x86_64", want: goTarget{arch: "amd64"}, ok: true},
+		{machine: "aarch64", want: goTarget{arch: "arm64"}, ok: true},
+		{machine: "arm64", want: goTarget{arch: "arm64"}, ok: true},
+		{machine: "armv7l", want: goTarget{arch: "arm", arm: "7"}, ok: true},
+		{machine: "armv6l", want: goTarget{arch: "arm", arm: "6"}, ok: true},
+		{machine: "s390x", want: goTarget{arch: "s390x"}, ok: true},
+		{machine: "sparc64"},
+	}
+	for _, tt := range tests {
+		got, ok := goArch(tt.machine)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("goArch(%q) = %+v, %v, want %+v, %v", tt.machine, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+
+	"github.com/dsvdev/testground/internal/container"
+)
+
+// coverDir is the GOCOVERDIR of a service started with WithCoverage.
+const coverDir = "/tmp/testground-cover"
+
+// coverStopTimeout is how long the service gets to exit after SIGTERM and
+// write its coverage counters.
+const coverStopTimeout = 30 * time.Second
+
+// profileMu serializes merges into a coverprofile shared by several services
+// of the same test binary, and guards written.
+var profileMu sync.Mutex
+
+// written holds the absolute paths of the profiles this process has written.
+// The first write replaces what an earlier run left in the file.
+var written = make(map[string]bool)
+
+// coverageFiles returns a ContainerFile that creates an empty, world-writable
+// GOCOVERDIR in the container, and a function that removes the temporary
+// host directory it is copied from.
+func coverageFiles() (testcontainers.ContainerFile, func(), error) {
+	tmp, err := os.MkdirTemp("", "testground-cover-")
+	if err != nil {
+		return testcontainers.ContainerFile{}, nil, err
+	}
+	// The directory is extracted under the parent of ContainerFilePath with
+	// its host name, so both must match.
+	dir := filepath.Join(tmp, filepath.Base(coverDir))
+	if err := os.Mkdir(dir, 0o777); err != nil {
+		os.RemoveAll(tmp)
+		return testcontainers.ContainerFile{}, nil, err
+	}
+	// Mkdir applies the umask; the service may run as any user.
+	if err := os.Chmod(dir, 0o777); err != nil {
+		os.RemoveAll(tmp)
+		return testcontainers.ContainerFile{}, nil, err
+	}
+	file := testcontainers.ContainerFile{
+		HostFilePath:      dir,
+		ContainerFilePath: coverDir,
+		FileMode:          0o777,
+	}
+	return file, func() { os.RemoveAll(tmp) }, nil
+}
+
+// collectCoverage stops the service so that it writes its counters, copies
+// GOCOVERDIR out of the container and merges it into the coverprofile.
+func collectCoverage(ctx context.Context, base *container.Base, profile string) error {
+	timeout := coverStopTimeout
+	if err := base.Stop(ctx, &timeout); err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "testground-covdata-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	if err := base.CopyDirFrom(ctx, coverDir, dir); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return errors.New("no coverage data: the service must be built with -cover and exit normally on SIGTERM")
+	}
+	return mergeProfile(ctx, dir, profile)
+}
+
+// mergeProfile converts the raw coverage data in covdataDir to the text
+// format and adds it to profile, see appendProfile. go tool cover merges the
+// counters of blocks that appear more than once.
+func mergeProfile(ctx context.Context, covdataDir, profile string) error {
+	var stderr bytes.Buffer
+	text := filepath.Join(covdataDir, "profile.txt")
+	cmd := exec.CommandContext(ctx, "go", "tool", "covdata", "textfmt", "-i="+covdataDir, "-o="+text)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("go tool covdata: %w\n%s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	data, err := os.ReadFile(text)
+	if err != nil {
+		return err
+	}
+	return appendProfile(profile, data)
+}
+
+// appendProfile writes the text-format profile data to profile on the first
+// call for it in this process, replacing the file of an earlier run, and
+// appends its blocks on later calls, which must have the same mode.
+func appendProfile(profile string, data []byte) error {
+	mode, blocks, _ := strings.Cut(string(data), "\n")
+
+	abs, err := filepath.Abs(profile)
+	if err != nil {
+		return err
+	}
+
+	profileMu.Lock()
+	defer profileMu.Unlock()
+
+	if !written[abs] {
+		if err := os.WriteFile(profile, data, 0o644); err != nil {
+			return err
+		}
+		written[abs] = true
+		return nil
+	}
+
+	existing, err := profileMode(profile)
+	if err != nil {
+		return err
+	}
+	if existing == "" {
+		return os.WriteFile(profile, data, 0o644)
+	}
+	if existing != mode {
+		return fmt.Errorf("cannot merge %q into %s with %q", mode, profile, existing)
+	}
+
+	f, err := os.OpenFile(profile, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(blocks); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// profileMode returns the "mode: ..." header of an existing profile, or an
+// empty string if the file does not exist or is empty.
+func profileMode(profile string) (string, error) {
+	f, err := os.Open(profile)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	if sc.Scan() {
+		return sc.Text(), nil
+	}
+	return "", sc.Err()
+}
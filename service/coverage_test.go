@@ -0,0 +1,38 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAppendProfile(t *testing.T) {
+	profile := filepath.Join(t.TempDir(), "cover.out")
+	// Left by an earlier run, so it must be replaced rather than extended.
+	if err := os.WriteFile(profile, []byte("mode: set\nexample.com/a/old.go:1.1,2.2 1 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	first := "mode: set\nexample.com/a/a.go:1.1,2.2 1 1\n"
+	second := "mode: set\nexample.com/a/b.go:3.1,4.2 1 0\n"
+
+	if err := appendProfile(profile, []byte(first)); err != nil {
+		t.Fatalf("appendProfile() first write error = %v", err)
+	}
+	if err := appendProfile(profile, []byte(second)); err != nil {
+		t.Fatalf("appendProfile() second write error = %v", err)
+	}
+
+	got, err := os.ReadFile(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "mode: set\nexample.com/a/a.go:1.1,2.2 1 1\nexample.com/a/b.go:3.1,4.2 1 0\n"
+	if string(got) != want {
+		t.Errorf("profile = %q, want %q", got, want)
+	}
+
+	if err := appendProfile(profile, []byte("mode: atomic\nexample.com/a/a.go:1.1,2.2 1 5\n")); err == nil {
+		t.Error("appendProfile() with a different mode: expected error")
+	}
+}
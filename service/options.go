@@ -1,26 +1,93 @@
 package service
 
 import (
+	"maps"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"time"
+
 	"github.com/dsvdev/testground"
 
 	"github.com/testcontainers/testcontainers-go/wait"
 )
 
 type config struct {
-	dockerfile  string
-	context     string
-	envs        map[string]string
-	port        string
-	networkName string
-	waitFor     wait.Strategy
+	dockerfile     string
+	context        string
+	buildArgs      map[string]*string
+	image          string
+	envs           map[string]string
+	port           string
+	ports          []string
+	cmd            []string
+	entrypoint     []string
+	networkName    string
+	networkAliases []string
+	volumes        []mount
+	binds          []mount
+	waitFor        wait.Strategy
+	health         *healthCheck
+	startupTimeout time.Duration
+	drainTimeout   time.Duration
+	replicas       int
+	dependsOn      []testground.HealthChecker
+	goPackage      string
+	baseImage      string
+	goArch         string
+	coverProfile   string
+
+	// binary is a build of goPackage shared by all replicas, so that it is
+	// compiled only once.
+	binary string
+}
+
+type mount struct {
+	source string
+	target string
 }
 
 func defaultConfig() config {
 	return config{
-		context: ".",
-		envs:    make(map[string]string),
-		waitFor: wait.ForListeningPort("8080/tcp"),
+		context:        ".",
+		buildArgs:      make(map[string]*string),
+		envs:           make(map[string]string),
+		baseImage:      "alpine:3.20",
+		startupTimeout: defaultStartupTimeout,
+		drainTimeout:   10 * time.Second,
+	}
+}
+
+// clone returns a copy of c that options can modify without affecting c.
+func (c config) clone() config {
+	c.buildArgs = maps.Clone(c.buildArgs)
+	c.envs = maps.Clone(c.envs)
+	c.ports = slices.Clone(c.ports)
+	c.cmd = slices.Clone(c.cmd)
+	c.entrypoint = slices.Clone(c.entrypoint)
+	c.networkAliases = slices.Clone(c.networkAliases)
+	c.volumes = slices.Clone(c.volumes)
+	c.binds = slices.Clone(c.binds)
+	c.dependsOn = slices.Clone(c.dependsOn)
+	return c
+}
+
+// exposedPorts returns the container ports to expose, the primary one first:
+// WithPort if set, otherwise the first of WithPorts, otherwise 8080.
+func (c config) exposedPorts() []string {
+	var ports []string
+	if c.port != "" {
+		ports = append(ports, c.port)
+	}
+	for _, p := range c.ports {
+		if !slices.Contains(ports, p) {
+			ports = append(ports, p)
+		}
+	}
+	if len(ports) == 0 {
+		ports = append(ports, "8080")
 	}
+	return ports
 }
 
 type Option func(*config)
@@ -49,14 +116,189 @@ func WithPort(port string) Option {
 	}
 }
 
+// WithPorts exposes additional container ports, e.g. gRPC and metrics next
+// to HTTP. Each gets its own host port; see MappedURL and MappedPort. Without
+// WithPort the first of them is the primary port used by URL and Port.
+func WithPorts(ports ...string) Option {
+	return func(c *config) {
+		c.ports = append(c.ports, ports...)
+	}
+}
+
+// WithImage runs a prebuilt image, e.g. one built earlier in CI, instead of
+// building from a Dockerfile.
+func WithImage(ref string) Option {
+	return func(c *config) {
+		c.image = ref
+	}
+}
+
+// WithBuildArg passes a build argument to the Dockerfile build.
+func WithBuildArg(key, value string) Option {
+	return func(c *config) {
+		c.buildArgs[key] = &value
+	}
+}
+
+// WithCmd overrides the image's CMD. With WithGoPackage these are the
+// arguments of the service binary.
+func WithCmd(args ...string) Option {
+	return func(c *config) {
+		c.cmd = args
+	}
+}
+
+// WithEntrypoint overrides the image's ENTRYPOINT.
+func WithEntrypoint(args ...string) Option {
+	return func(c *config) {
+		c.entrypoint = args
+	}
+}
+
+// WithVolume mounts the named Docker volume at target. The volume is created
+// if it does not exist and outlives the container.
+func WithVolume(name, target string) Option {
+	return func(c *config) {
+		c.volumes = append(c.volumes, mount{source: name, target: target})
+	}
+}
+
+// WithBindMount mounts the host path source at target. Bind mounts only work
+// with a local Docker engine; prefer CopyFileTo where possible.
+func WithBindMount(source, target string) Option {
+	return func(c *config) {
+		if abs, err := filepath.Abs(source); err == nil {
+			source = abs
+		}
+		c.binds = append(c.binds, mount{source: source, target: target})
+	}
+}
+
 func WithNetwork(n *testground.Network) Option {
 	return func(c *config) {
 		c.networkName = n.Name()
 	}
 }
 
+// WithNetworkAlias adds a DNS alias for the service inside the network set
+// with WithNetwork. It can be given more than once.
+func WithNetworkAlias(alias string) Option {
+	return func(c *config) {
+		c.networkAliases = append(c.networkAliases, alias)
+	}
+}
+
+// WithWaitFor sets a custom readiness wait strategy. It is combined with
+// WithHealthCheck if both are given. Default: the primary port is listening.
 func WithWaitFor(s wait.Strategy) Option {
 	return func(c *config) {
 		c.waitFor = s
 	}
 }
+
+// WithHealthCheck makes New wait until GET path on the primary port returns
+// expectStatus. Healthy uses the same check.
+func WithHealthCheck(path string, expectStatus int) Option {
+	return func(c *config) {
+		c.health = &healthCheck{path: path, status: expectStatus}
+	}
+}
+
+// WithHealthCheckJSON is like WithHealthCheck with a 200 status, and
+// additionally requires match to accept the decoded JSON body, e.g. to wait
+// until {"status": "UP"}.
+func WithHealthCheckJSON(path string, match func(body map[string]any) bool) Option {
+	return func(c *config) {
+		c.health = &healthCheck{path: path, status: http.StatusOK, match: match}
+	}
+}
+
+// WithStartupTimeout bounds how long New waits for the dependencies and the
+// service to become ready. On timeout the error describes what was awaited
+// and includes the last container logs. Default: 60s.
+func WithStartupTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.startupTimeout = d
+	}
+}
+
+// WithDrainTimeout sets how long Replace and Rebuild give the old container
+// to finish in-flight requests after SIGTERM before it is killed.
+// Default: 10s.
+func WithDrainTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.drainTimeout = d
+	}
+}
+
+// WithReplicas starts n containers from the same build, made once, behind an nginx
+// load balancer. URL, Port, MappedURL and the network aliases point at the
+// balancer, which spreads HTTP requests on the primary port round-robin and
+// connections on the other ports. Use ReplicaURL to reach a single replica
+// and KillReplica to take one down. Without WithNetwork a private network is
+// created for them.
+//
+// Stop, Start, Restart, Pause, Unpause and CopyFileTo act on every replica
+// and Healthy requires every replica to pass; ContainerID is the balancer's.
+// Logs, Exec and CopyFileFrom return an error, use ReplicaLogs and
+// ExecReplica instead. Replace is not supported.
+func WithReplicas(n int) Option {
+	return func(c *config) {
+		c.replicas = n
+	}
+}
+
+// WithDependsOn makes New wait until every dependency is healthy before it
+// starts the service, e.g. a database that is still running migrations.
+func WithDependsOn(deps ...testground.HealthChecker) Option {
+	return func(c *config) {
+		c.dependsOn = append(c.dependsOn, deps...)
+	}
+}
+
+// WithGoPackage builds the service from a Go main package instead of a
+// Dockerfile. The package is cross-compiled on the host with the local Go
+// toolchain and copied into WithBaseImage, so rebuilds reuse the Go build
+// cache. pkg is resolved like an argument to go build, relative to the
+// working directory of the test, e.g. "./cmd/server". WithDockerfile and
+// WithBuildContext are ignored.
+func WithGoPackage(pkg string) Option {
+	return func(c *config) {
+		c.goPackage = pkg
+	}
+}
+
+// WithBaseImage sets the image the binary built by WithGoPackage is copied
+// into. The binary is static, so any image works. Default: "alpine:3.20",
+// which keeps a shell around for Exec.
+func WithBaseImage(ref string) Option {
+	return func(c *config) {
+		c.baseImage = ref
+	}
+}
+
+// WithGoArch sets the GOARCH WithGoPackage compiles for, e.g. "amd64" to
+// run the service under emulation on an arm64 Docker engine. Default: the
+// architecture of the Docker engine, which differs from the host's when the
+// engine is remote, with GOARM set for ARMv6 and ARMv7 engines.
+func WithGoArch(arch string) Option {
+	return func(c *config) {
+		c.goArch = arch
+	}
+}
+
+// WithCoverage collects Go coverage from the service into the coverprofile at
+// profile, which can be viewed with go tool cover. With WithGoPackage the
+// binary is built with -cover; with a Dockerfile the image must build it with
+// -cover itself. GOCOVERDIR is set inside the container, and Terminate stops
+// the service with SIGTERM, waits for it to exit and merges its counters into
+// profile. The first service of a test binary to finish replaces a profile
+// left by an earlier run; later ones append to it.
+//
+// The service must exit normally on SIGTERM, by returning from main or
+// calling os.Exit: a process killed by a signal writes no coverage data.
+func WithCoverage(profile string) Option {
+	return func(c *config) {
+		c.coverProfile = profile
+	}
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestConfig_ExposedPorts(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want []string
+	}{
+		{name: "default", want: []string{"8080"}},
+		{name: "port", opts: []Option{WithPort("9000")}, want: []string{"9000"}},
+		{name: "ports", opts: []Option{WithPorts("8080", "9090")}, want: []string{"8080", "9090"}},
+		{
+			name: "port first, duplicates removed",
+			opts: []Option{WithPorts("9090", "8081"), WithPort("8081")},
+			want: []string{"8081", "9090"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defaultConfig()
+			for _, opt := range tt.opts {
+				opt(&cfg)
+			}
+			if got := cfg.exposedPorts(); !slices.Equal(got, tt.want) {
+				t.Errorf("exposedPorts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// defaultStartupTimeout bounds how long New waits for the service and its
+// dependencies to become ready.
+const defaultStartupTimeout = 60 * time.Second
+
+// healthCheck is an HTTP readiness probe against the primary port.
+type healthCheck struct {
+	path   string
+	status int
+	// match, if set, must accept the decoded JSON response body.
+	match func(body map[string]any) bool
+}
+
+// matchBody reports whether the JSON response body is accepted by match.
+func (h *healthCheck) matchBody(body io.Reader) bool {
+	var v map[string]any
+	if err := json.NewDecoder(body).Decode(&v); err != nil {
+		return false
+	}
+	return h.match(v)
+}
+
+// readiness returns the wait strategy for the container: WithWaitFor and
+// WithHealthCheck combined, or a listening primary port if neither is set.
+func (c config) readiness() wait.Strategy {
+	port := nat.Port(c.exposedPorts()[0] + "/tcp")
+
+	var strategies []wait.Strategy
+	if c.waitFor != nil {
+		strategies = append(strategies, c.waitFor)
+	}
+	if h := c.health; h != nil {
+		probe := wait.ForHTTP(h.path).
+			WithPort(port).
+			WithStatusCodeMatcher(func(status int) bool { return status == h.status })
+		if h.match != nil {
+			probe = probe.WithResponseMatcher(h.matchBody)
+		}
+		strategies = append(strategies, probe)
+	}
+	if len(strategies) == 0 {
+		strategies = append(strategies, wait.ForListeningPort(port))
+	}
+	return wait.ForAll(strategies...).
+		WithDeadline(c.startupTimeout).
+		WithStartupTimeoutDefault(c.startupTimeout)
+}
+
+// describeReadiness is a human-readable form of readiness for start errors.
+func (c config) describeReadiness() string {
+	var parts []string
+	if c.waitFor != nil {
+		parts = append(parts, fmt.Sprint(c.waitFor))
+	}
+	if h := c.health; h != nil {
+		desc := fmt.Sprintf("GET %s returns %d", h.path, h.status)
+		if h.match != nil {
+			desc += " with a matching JSON body"
+		}
+		parts = append(parts, desc)
+	}
+	if len(parts) == 0 {
+		parts = append(parts, fmt.Sprintf("port %s is listening", c.exposedPorts()[0]))
+	}
+	return strings.Join(parts, " and ")
+}
+
+// Healthy reports whether the service passes its health check, or, without
+// WithHealthCheck, whether its primary port accepts connections. With
+// WithReplicas every replica must pass.
+func (c *Container) Healthy(ctx context.Context) error {
+	_, cfg := c.snapshot()
+	return c.eachReplica(func(r *replica) error {
+		return cfg.checkHealth(ctx, r.base.Host(), r.hostPorts[cfg.exposedPorts()[0]])
+	})
+}
+
+// checkHealth runs the health check against the primary port bound to
+// host:port.
+func (c config) checkHealth(ctx context.Context, host, port string) error {
+	h := c.health
+	if h == nil {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	url := fmt.Sprintf("http://%s%s", net.JoinHostPort(host, port), h.path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != h.status {
+		return fmt.Errorf("GET %s: status %d, want %d", h.path, resp.StatusCode, h.status)
+	}
+	if h.match != nil && !h.matchBody(resp.Body) {
+		return fmt.Errorf("GET %s: response body does not match", h.path)
+	}
+	return nil
+}
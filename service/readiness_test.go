@@ -0,0 +1,48 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHealthCheck_MatchBody(t *testing.T) {
+	h := &healthCheck{match: func(body map[string]any) bool { return body["status"] == "UP" }}
+
+	if !h.matchBody(strings.NewReader(`{"status": "UP"}`)) {
+		t.Error(`matchBody({"status": "UP"}) = false, want true`)
+	}
+	if h.matchBody(strings.NewReader(`{"status": "STARTING"}`)) {
+		t.Error(`matchBody({"status": "STARTING"}) = true, want false`)
+	}
+	if h.matchBody(strings.NewReader(`not json`)) {
+		t.Error("matchBody(not json) = true, want false")
+	}
+}
+
+func TestConfig_DescribeReadiness(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{name: "default", opts: []Option{WithPort("9000")}, want: "port 9000 is listening"},
+		{name: "health check", opts: []Option{WithHealthCheck("/healthz", 204)}, want: "GET /healthz returns 204"},
+		{
+			name: "json",
+			opts: []Option{WithHealthCheckJSON("/health", func(map[string]any) bool { return true })},
+			want: "GET /health returns 200 with a matching JSON body",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defaultConfig()
+			for _, opt := range tt.opts {
+				opt(&cfg)
+			}
+			if got := cfg.describeReadiness(); got != tt.want {
+				t.Errorf("describeReadiness() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dsvdev/testground/internal/container"
+)
+
+// Rebuild rebuilds the service from its sources and swaps the running
+// container for the new one, see Replace.
+func (c *Container) Rebuild(ctx context.Context) error {
+	return c.Replace(ctx)
+}
+
+// Replace starts a new container from the current configuration with opts
+// applied on top, and swaps it in for the running one. Dependencies keep
+// running.
+//
+// The image or binary is built while the old container still serves
+// requests. The old container is then stopped with SIGTERM, giving it
+// WithDrainTimeout to finish in-flight requests, and the new one is started
+// on the same host ports and network aliases, so URL, MappedURL and clients
+// created from them keep working. If the new container fails to start, the
+// old one is started again and the error is returned.
+func (c *Container) Replace(ctx context.Context, opts ...Option) error {
+	if c.replicas != nil {
+		return errors.New("service: Replace is not supported with WithReplicas")
+	}
+
+	c.replaceMu.Lock()
+	defer c.replaceMu.Unlock()
+
+	old, oldCfg := c.snapshot()
+	cfg := oldCfg.clone()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.image != "" && cfg.goPackage != "" {
+		return errors.New("service: WithImage and WithGoPackage are mutually exclusive")
+	}
+
+	c.mu.RLock()
+	hostPorts := maps.Clone(c.hostPorts)
+	c.mu.RUnlock()
+	var added []string
+	for _, port := range cfg.exposedPorts() {
+		if _, ok := hostPorts[port]; !ok {
+			added = append(added, port)
+		}
+	}
+	extra, err := freePorts(added)
+	if err != nil {
+		return fmt.Errorf("service: replace: %w", err)
+	}
+	maps.Copy(hostPorts, extra)
+
+	next, err := create(ctx, cfg, hostPorts)
+	if err != nil {
+		return fmt.Errorf("service: replace: %w", err)
+	}
+
+	if err := drain(ctx, old, oldCfg); err != nil {
+		next.Terminate(context.WithoutCancel(ctx)) //nolint:errcheck
+		return fmt.Errorf("service: replace: stop previous container: %w", err)
+	}
+
+	if err := start(ctx, next, cfg); err != nil {
+		// Bring the previous container back so the test keeps a working
+		// service.
+		if rerr := old.Start(ctx); rerr != nil {
+			err = errors.Join(err, fmt.Errorf("restart previous container: %w", rerr))
+		}
+		return fmt.Errorf("service: replace: %w", err)
+	}
+
+	c.mu.Lock()
+	c.base, c.cfg, c.hostPorts = next, cfg, hostPorts
+	c.mu.Unlock()
+
+	if err := old.Terminate(ctx); err != nil {
+		return fmt.Errorf("service: replace: remove previous container: %w", err)
+	}
+	return nil
+}
+
+// drain stops the container gracefully, collecting its coverage first if
+// enabled, so that the host ports it holds are released.
+func drain(ctx context.Context, base *container.Base, cfg config) error {
+	if cfg.coverProfile != "" {
+		return collectCoverage(ctx, base, cfg.coverProfile)
+	}
+	return base.Stop(ctx, &cfg.drainTimeout)
+}
+
+// Watch polls the files under root and calls Rebuild whenever one of them
+// changes, until ctx is done. Hidden directories such as .git are skipped.
+// After every rebuild onRebuild, if not nil, is called with its result; a
+// failed rebuild leaves the previous container running. Watch blocks, so run
+// it in its own goroutine, e.g. from TestMain during local development.
+func (c *Container) Watch(ctx context.Context, root string, onRebuild func(error)) error {
+	last, err := treeSignature(root)
+	if err != nil {
+		return fmt.Errorf("service: watch %s: %w", root, err)
+	}
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		sig, err := treeSignature(root)
+		if err != nil || sig == last {
+			continue
+		}
+		last = sig
+
+		err = c.Rebuild(ctx)
+		if onRebuild != nil {
+			onRebuild(err)
+		}
+	}
+}
+
+// watchInterval is how often Watch scans the source tree.
+const watchInterval = time.Second
+
+// treeSignature summarizes the path, size and modification time of every
+// file under root, so that any edit, addition or removal changes it.
+func treeSignature(root string) (string, error) {
+	var entries []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil // removed while walking
+			}
+			return err
+		}
+		entries = append(entries, fmt.Sprintf("%s %d %d", path, info.Size(), info.ModTime().UnixNano()))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	slices.Sort(entries)
+	return container.ConfigHash(entries), nil
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConfig_CloneIsIndependent(t *testing.T) {
+	cfg := defaultConfig()
+	WithEnv("A", "1")(&cfg)
+	WithPorts("8080")(&cfg)
+
+	clone := cfg.clone()
+	WithEnv("A", "2")(&clone)
+	WithPorts("9090")(&clone)
+
+	if cfg.envs["A"] != "1" {
+		t.Errorf("original env A = %q after modifying the clone, want 1", cfg.envs["A"])
+	}
+	if len(cfg.ports) != 1 {
+		t.Errorf("original ports = %v after modifying the clone, want [8080]", cfg.ports)
+	}
+}
+
+func TestTreeSignature(t *testing.T) {
+	root := t.TempDir()
+	file := filepath.Join(root, "main.go")
+	if err := os.WriteFile(file, []byte("package main"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	sig := func() string {
+		t.Helper()
+		s, err := treeSignature(root)
+		if err != nil {
+			t.Fatalf("treeSignature() error = %v", err)
+		}
+		return s
+	}
+
+	before := sig()
+	if err := os.WriteFile(filepath.Join(root, ".git", "index"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := sig(); got != before {
+		t.Error("signature changed for a file in a hidden directory")
+	}
+
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(file, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if got := sig(); got == before {
+		t.Error("signature did not change after a file was modified")
+	}
+}
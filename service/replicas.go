@@ -0,0 +1,321 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/dsvdev/testground"
+	"github.com/dsvdev/testground/internal/container"
+)
+
+// balancerImage runs the load balancer in front of replicas. nginx 1.27.3 is
+// the first open source release that re-resolves upstream names, which keeps
+// a replica reachable after KillReplica and StartReplica change its address.
+const balancerImage = "nginx:1.27-alpine"
+
+// replica is one of the containers started by WithReplicas.
+type replica struct {
+	base      *container.Base
+	hostPorts map[string]string
+}
+
+// newReplicated starts cfg.replicas containers and a load balancer bound to
+// hostPorts in front of them.
+func newReplicated(ctx context.Context, cfg config, hostPorts map[string]string) (_ *Container, err error) {
+	c := &Container{cfg: cfg, hostPorts: hostPorts}
+	defer func() {
+		if err != nil {
+			c.terminateReplicas(context.WithoutCancel(ctx)) //nolint:errcheck
+		}
+	}()
+
+	// Replicas and the balancer need a network to reach each other.
+	if cfg.networkName == "" {
+		net, err := testground.NewNetwork(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.ownNet = net
+		cfg.networkName = net.Name()
+	}
+
+	// The configured aliases belong to the balancer; every replica gets its
+	// own, unique across replicated services sharing a network.
+	prefix := "testground-" + strings.ToLower(rand.Text()[:8])
+	replicaCfg := cfg.clone()
+	// Compile a Go package only once; every replica gets the same binary.
+	if cfg.image == "" && cfg.goPackage != "" {
+		bin, err := buildBinary(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(filepath.Dir(bin))
+		replicaCfg.binary = bin
+	}
+	var upstreams []string
+	for i := range cfg.replicas {
+		alias := fmt.Sprintf("%s-%d", prefix, i)
+		rcfg := replicaCfg.clone()
+		rcfg.networkAliases = []string{alias}
+
+		ports, err := freePorts(rcfg.exposedPorts())
+		if err != nil {
+			return nil, err
+		}
+		base, err := create(ctx, rcfg, ports)
+		if err != nil {
+			return nil, err
+		}
+		if err := start(ctx, base, rcfg); err != nil {
+			return nil, fmt.Errorf("replica %d: %w", i, err)
+		}
+		c.replicas = append(c.replicas, &replica{base: base, hostPorts: ports})
+		upstreams = append(upstreams, alias)
+
+		// Build a Dockerfile only once; the other replicas run its image.
+		if i == 0 && cfg.image == "" && cfg.goPackage == "" {
+			id, err := base.Image(ctx)
+			if err != nil {
+				return nil, err
+			}
+			replicaCfg.image = id
+		}
+	}
+	c.base = c.replicas[0].base
+
+	lb, err := startBalancer(ctx, cfg, hostPorts, upstreams)
+	if err != nil {
+		return nil, fmt.Errorf("service: start load balancer: %w", err)
+	}
+	c.balancer = lb
+	return c, nil
+}
+
+// startBalancer starts nginx on cfg's network and aliases, bound to
+// hostPorts. The primary port is balanced per HTTP request, so that clients
+// with keep-alive connections still reach every replica; other ports are
+// balanced per TCP connection.
+func startBalancer(ctx context.Context, cfg config, hostPorts map[string]string, upstreams []string) (*container.Base, error) {
+	dir, err := os.MkdirTemp("", "testground-balancer-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	conf := filepath.Join(dir, "nginx.conf")
+	if err := os.WriteFile(conf, []byte(balancerConfig(cfg.exposedPorts(), upstreams)), 0o644); err != nil {
+		return nil, err
+	}
+
+	ports := cfg.exposedPorts()
+	req := testcontainers.ContainerRequest{
+		Image: balancerImage,
+		Files: []testcontainers.ContainerFile{{
+			HostFilePath:      conf,
+			ContainerFilePath: "/etc/nginx/nginx.conf",
+			FileMode:          0o644,
+		}},
+		Networks:   []string{cfg.networkName},
+		WaitingFor: wait.ForListeningPort(nat.Port(ports[0] + "/tcp")),
+	}
+	for _, port := range ports {
+		req.ExposedPorts = append(req.ExposedPorts, fmt.Sprintf("%s:%s/tcp", hostPorts[port], port))
+	}
+	if len(cfg.networkAliases) > 0 {
+		req.NetworkAliases = map[string][]string{cfg.networkName: cfg.networkAliases}
+	}
+	return container.Start(ctx, req, nat.Port(ports[0]+"/tcp"))
+}
+
+// balancerConfig renders the nginx configuration for ports, the primary one
+// first, forwarding to the upstream hosts.
+func balancerConfig(ports, upstreams []string) string {
+	var b strings.Builder
+	upstream := func(name, port string) {
+		fmt.Fprintf(&b, "    upstream %s {\n        zone %s 64k;\n", name, name)
+		for _, host := range upstreams {
+			fmt.Fprintf(&b, "        server %s:%s resolve max_fails=1 fail_timeout=1s;\n", host, port)
+		}
+		b.WriteString("    }\n")
+	}
+
+	b.WriteString("worker_processes 1;\nevents { worker_connections 1024; }\n\n")
+
+	primary := ports[0]
+	b.WriteString("http {\n    resolver 127.0.0.11 valid=1s ipv6=off;\n")
+	upstream("replicas_"+primary, primary)
+	fmt.Fprintf(&b, `    server {
+        listen %s;
+        location / {
+            proxy_pass http://replicas_%s;
+            proxy_http_version 1.1;
+            proxy_set_header Connection "";
+            proxy_set_header Host $http_host;
+            proxy_next_upstream error timeout http_502 http_503;
+        }
+    }
+}
+`, primary, primary)
+
+	if len(ports) > 1 {
+		b.WriteString("\nstream {\n    resolver 127.0.0.11 valid=1s ipv6=off;\n")
+		for _, port := range ports[1:] {
+			upstream("replicas_"+port, port)
+			fmt.Fprintf(&b, "    server {\n        listen %s;\n        proxy_pass replicas_%s;\n    }\n", port, port)
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+// freePorts binds every port to a free host port.
+func freePorts(ports []string) (map[string]string, error) {
+	hostPorts := make(map[string]string, len(ports))
+	for _, port := range ports {
+		hostPort, err := container.FreePort()
+		if err != nil {
+			return nil, fmt.Errorf("find free port: %w", err)
+		}
+		hostPorts[port] = strconv.Itoa(hostPort)
+	}
+	return hostPorts, nil
+}
+
+// replicaList returns the replicas, or the single container as replica 0
+// when WithReplicas is not used.
+func (c *Container) replicaList() []*replica {
+	if c.replicas != nil {
+		return c.replicas
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return []*replica{{base: c.base, hostPorts: c.hostPorts}}
+}
+
+// replica returns replica i or an error if there is none.
+func (c *Container) replica(i int) (*replica, error) {
+	list := c.replicaList()
+	if i < 0 || i >= len(list) {
+		return nil, fmt.Errorf("service: replica %d out of range [0, %d)", i, len(list))
+	}
+	return list[i], nil
+}
+
+// Replicas returns the number of replicas, 1 without WithReplicas.
+func (c *Container) Replicas() int {
+	return len(c.replicaList())
+}
+
+// ReplicaURL returns "http://host:port" of replica i on the primary port,
+// bypassing the load balancer. Without WithReplicas, ReplicaURL(0) equals
+// URL. It returns an empty string for an index out of range.
+func (c *Container) ReplicaURL(i int) string {
+	r, err := c.replica(i)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("http://%s:%s", r.base.Host(), r.hostPorts[c.primaryPort()])
+}
+
+// KillReplica kills replica i with SIGKILL, without a grace period, as a
+// crashed instance would die. The load balancer routes around it. The
+// container is kept and can be brought back with StartReplica.
+func (c *Container) KillReplica(ctx context.Context, i int) error {
+	r, err := c.replica(i)
+	if err != nil {
+		return err
+	}
+	var noGrace time.Duration
+	return r.base.Stop(ctx, &noGrace)
+}
+
+// StartReplica starts replica i again after KillReplica and waits until it
+// is ready. The load balancer picks it up within a second.
+func (c *Container) StartReplica(ctx context.Context, i int) error {
+	r, err := c.replica(i)
+	if err != nil {
+		return err
+	}
+	return r.base.Start(ctx)
+}
+
+// ExecReplica runs cmd inside replica i, like Exec.
+func (c *Container) ExecReplica(ctx context.Context, i int, cmd []string) (exitCode int, stdout, stderr string, err error) {
+	r, err := c.replica(i)
+	if err != nil {
+		return 0, "", "", err
+	}
+	return r.base.Exec(ctx, cmd)
+}
+
+// eachReplica calls fn for every replica and joins the errors.
+func (c *Container) eachReplica(fn func(*replica) error) error {
+	var errs []error
+	for i, r := range c.replicaList() {
+		if err := fn(r); err != nil {
+			errs = append(errs, fmt.Errorf("replica %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// eachContainer calls fn for every replica and then the load balancer, and
+// joins the errors.
+func (c *Container) eachContainer(fn func(*container.Base) error) error {
+	err := c.eachReplica(func(r *replica) error { return fn(r.base) })
+	if c.balancer != nil {
+		if lbErr := fn(c.balancer); lbErr != nil {
+			err = errors.Join(err, fmt.Errorf("load balancer: %w", lbErr))
+		}
+	}
+	return err
+}
+
+// ReplicaLogs returns the output of replica i produced so far. The caller
+// must close the returned reader.
+func (c *Container) ReplicaLogs(ctx context.Context, i int) (io.ReadCloser, error) {
+	r, err := c.replica(i)
+	if err != nil {
+		return nil, err
+	}
+	return r.base.Logs(ctx)
+}
+
+// terminateReplicas removes the load balancer, every replica and the
+// network created for them, collecting coverage from each replica first if
+// enabled.
+func (c *Container) terminateReplicas(ctx context.Context) error {
+	var errs []error
+	if c.balancer != nil {
+		if err := c.balancer.Terminate(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("terminate load balancer: %w", err))
+		}
+	}
+	for i, r := range c.replicas {
+		if c.cfg.coverProfile != "" {
+			if err := collectCoverage(ctx, r.base, c.cfg.coverProfile); err != nil {
+				errs = append(errs, fmt.Errorf("replica %d: collect coverage: %w", i, err))
+			}
+		}
+		if err := r.base.Terminate(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("replica %d: %w", i, err))
+		}
+	}
+	if c.ownNet != nil {
+		if err := c.ownNet.Terminate(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("terminate network: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestBalancerConfig(t *testing.T) {
+	conf := balancerConfig([]string{"8080", "9090"}, []string{"r-0", "r-1"})
+
+	for _, want := range []string{
+		"listen 8080;",
+		"proxy_pass http://replicas_8080;",
+		"server r-0:8080 resolve",
+		"server r-1:8080 resolve",
+		"stream {",
+		"listen 9090;",
+		"proxy_pass replicas_9090;",
+		"server r-1:9090 resolve",
+	} {
+		if !strings.Contains(conf, want) {
+			t.Errorf("balancerConfig() does not contain %q:\n%s", want, conf)
+		}
+	}
+}
+
+func TestBalancerConfig_HTTPOnly(t *testing.T) {
+	conf := balancerConfig([]string{"8080"}, []string{"r-0"})
+	if strings.Contains(conf, "stream {") {
+		t.Errorf("balancerConfig() has a stream block without extra ports:\n%s", conf)
+	}
+}
+
+func TestContainer_ReplicasRejectSingleContainerMethods(t *testing.T) {
+	ctx := context.Background()
+	c := &Container{replicas: []*replica{{}, {}}}
+
+	if _, err := c.Logs(ctx); err == nil {
+		t.Error("Logs() error = nil, want error with replicas")
+	}
+	if _, _, _, err := c.Exec(ctx, []string{"true"}); err == nil {
+		t.Error("Exec() error = nil, want error with replicas")
+	}
+	if err := c.CopyFileFrom(ctx, "/etc/hostname", t.TempDir()+"/hostname"); err == nil {
+		t.Error("CopyFileFrom() error = nil, want error with replicas")
+	}
+	if _, _, _, err := c.ExecReplica(ctx, 2, []string{"true"}); err == nil {
+		t.Error("ExecReplica(2) error = nil, want out of range error")
+	}
+}
@@ -2,17 +2,41 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
 
+	dockercontainer "github.com/docker/docker/api/types/container"
 	"github.com/docker/go-connections/nat"
 	"github.com/testcontainers/testcontainers-go"
 
+	"github.com/dsvdev/testground"
 	"github.com/dsvdev/testground/internal/container"
 )
 
 type Container struct {
+	// mu guards the fields below, which Replace swaps.
+	mu   sync.RWMutex
 	base *container.Base
 	cfg  config
+	// hostPorts maps every exposed container port to the host port it is
+	// bound to.
+	hostPorts map[string]string
+
+	// replaceMu serializes Replace calls.
+	replaceMu sync.Mutex
+
+	// With WithReplicas, replicas holds every replica, base is replica 0,
+	// balancer is the load balancer bound to hostPorts and ownNet the network
+	// created for them if none was given.
+	replicas []*replica
+	balancer *container.Base
+	ownNet   *testground.Network
 }
 
 func New(ctx context.Context, opts ...Option) (*Container, error) {
@@ -20,39 +44,306 @@ func New(ctx context.Context, opts ...Option) (*Container, error) {
 	for _, opt := range opts {
 		opt(&cfg)
 	}
+	if cfg.image != "" && cfg.goPackage != "" {
+		return nil, errors.New("service: WithImage and WithGoPackage are mutually exclusive")
+	}
+
+	// Bind explicit host ports: Docker keeps explicit bindings across Stop
+	// and Start, so URL and MappedURL stay valid after a restart.
+	hostPorts, err := freePorts(cfg.exposedPorts())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cfg.dependsOn) > 0 {
+		depCtx, cancel := context.WithTimeout(ctx, cfg.startupTimeout)
+		err := testground.WaitHealthy(depCtx, cfg.dependsOn...)
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("service: dependencies not healthy within %s: %w", cfg.startupTimeout, err)
+		}
+	}
+
+	if cfg.replicas > 1 {
+		return newReplicated(ctx, cfg, hostPorts)
+	}
+
+	base, err := create(ctx, cfg, hostPorts)
+	if err != nil {
+		return nil, err
+	}
+	if err := start(ctx, base, cfg); err != nil {
+		return nil, err
+	}
+	return &Container{base: base, cfg: cfg, hostPorts: hostPorts}, nil
+}
+
+// start starts a container returned by create and waits until it is ready.
+// On failure the container is removed.
+func start(ctx context.Context, base *container.Base, cfg config) error {
+	if err := base.Start(ctx); err != nil {
+		base.Terminate(context.WithoutCancel(ctx)) //nolint:errcheck
+		return fmt.Errorf("service: start (ready when %s, timeout %s): %w", cfg.describeReadiness(), cfg.startupTimeout, err)
+	}
+	return nil
+}
 
-	exposedPort := nat.Port(cfg.port + "/tcp")
+// create builds the image or binary and creates, but does not start, the
+// container described by cfg, bound to hostPorts.
+func create(ctx context.Context, cfg config, hostPorts map[string]string) (*container.Base, error) {
+	ports := cfg.exposedPorts()
 
 	req := testcontainers.ContainerRequest{
-		FromDockerfile: testcontainers.FromDockerfile{
+		Env:        cfg.envs,
+		WaitingFor: cfg.readiness(),
+		Entrypoint: cfg.entrypoint,
+		Cmd:        cfg.cmd,
+	}
+	for _, port := range ports {
+		req.ExposedPorts = append(req.ExposedPorts, fmt.Sprintf("%s:%s/tcp", hostPorts[port], port))
+	}
+
+	if cfg.coverProfile != "" {
+		file, cleanup, err := coverageFiles()
+		if err != nil {
+			return nil, fmt.Errorf("prepare coverage directory: %w", err)
+		}
+		defer cleanup()
+		req.Files = append(req.Files, file)
+		req.Env = maps.Clone(cfg.envs)
+		req.Env["GOCOVERDIR"] = coverDir
+	}
+
+	switch {
+	case cfg.image != "":
+		req.Image = cfg.image
+	case cfg.goPackage != "":
+		bin := cfg.binary
+		if bin == "" {
+			var err error
+			if bin, err = buildBinary(ctx, cfg); err != nil {
+				return nil, err
+			}
+			// The file is copied when the container is created, so the
+			// build directory is not needed once create returns.
+			defer os.RemoveAll(filepath.Dir(bin))
+		}
+
+		req.Image = cfg.baseImage
+		req.Files = append(req.Files, testcontainers.ContainerFile{
+			HostFilePath:      bin,
+			ContainerFilePath: binaryPath,
+			FileMode:          0o755,
+		})
+		if req.Entrypoint == nil {
+			req.Entrypoint = []string{binaryPath}
+		}
+	default:
+		req.FromDockerfile = testcontainers.FromDockerfile{
 			Context:    cfg.context,
 			Dockerfile: cfg.dockerfile,
-		},
-		ExposedPorts: []string{string(exposedPort)},
-		Env:          cfg.envs,
-		WaitingFor:   cfg.waitFor,
+			BuildArgs:  cfg.buildArgs,
+		}
 	}
 
 	if cfg.networkName != "" {
 		req.Networks = []string{cfg.networkName}
+		if len(cfg.networkAliases) > 0 {
+			req.NetworkAliases = map[string][]string{
+				cfg.networkName: cfg.networkAliases,
+			}
+		}
+	}
+
+	for _, v := range cfg.volumes {
+		req.Mounts = append(req.Mounts, testcontainers.VolumeMount(v.source, testcontainers.ContainerMountTarget(v.target)))
+	}
+	if len(cfg.binds) > 0 {
+		req.HostConfigModifier = func(hc *dockercontainer.HostConfig) {
+			for _, b := range cfg.binds {
+				hc.Binds = append(hc.Binds, b.source+":"+b.target)
+			}
+		}
 	}
 
-	base, err := container.Start(ctx, req, exposedPort)
+	base, err := container.Create(ctx, req, nat.Port(ports[0]+"/tcp"))
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("service: %w", err)
 	}
+	return base, nil
+}
 
-	return &Container{base: base, cfg: cfg}, nil
+// current returns the running container, which Replace may swap.
+func (c *Container) current() *container.Base {
+	base, _ := c.snapshot()
+	return base
 }
 
+// snapshot returns the running container together with its configuration.
+func (c *Container) snapshot() (*container.Base, config) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.base, c.cfg
+}
+
+// URL returns "http://host:port" of the primary port. With WithReplicas it
+// points at the load balancer.
 func (c *Container) URL() string {
-	return fmt.Sprintf("http://%s:%s", c.base.Host(), c.base.Port())
+	return c.MappedURL(c.primaryPort())
 }
 
 func (c *Container) Port() string {
-	return c.base.Port()
+	return c.MappedPort(c.primaryPort())
+}
+
+func (c *Container) primaryPort() string {
+	_, cfg := c.snapshot()
+	return cfg.exposedPorts()[0]
 }
 
+// MappedURL returns "http://host:port" for one of the container ports exposed
+// with WithPort or WithPorts, e.g. a metrics or admin port. It returns an
+// empty string for a port that was not exposed.
+func (c *Container) MappedURL(port string) string {
+	hostPort := c.MappedPort(port)
+	if hostPort == "" {
+		return ""
+	}
+	return fmt.Sprintf("http://%s:%s", c.current().Host(), hostPort)
+}
+
+// MappedPort returns the host port bound to one of the exposed container
+// ports, e.g. for a gRPC client. It returns an empty string for a port that
+// was not exposed.
+func (c *Container) MappedPort(port string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.hostPorts[port]
+}
+
+// ContainerID returns the Docker ID of the service container, e.g. for
+// testground.Network.Disconnect. With WithReplicas it is the load balancer,
+// which holds the network aliases.
+func (c *Container) ContainerID() string {
+	if c.balancer != nil {
+		return c.balancer.ID()
+	}
+	return c.current().ID()
+}
+
+// Logs returns the service's stdout and stderr produced so far. The caller
+// must close the returned reader. With WithReplicas it returns an error; use
+// ReplicaLogs.
+func (c *Container) Logs(ctx context.Context) (io.ReadCloser, error) {
+	if c.replicas != nil {
+		return nil, errors.New("service: Logs is not supported with WithReplicas, use ReplicaLogs")
+	}
+	return c.current().Logs(ctx)
+}
+
+// LogsSince returns the service's stdout and stderr produced since the
+// Docker engine time since. The caller must close the returned reader. With
+// WithReplicas it returns an error, like Logs.
+func (c *Container) LogsSince(ctx context.Context, since time.Time) (io.ReadCloser, error) {
+	if c.replicas != nil {
+		return nil, errors.New("service: LogsSince is not supported with WithReplicas, use ReplicaLogs")
+	}
+	return c.current().LogsSince(ctx, since)
+}
+
+// Exec runs cmd inside the service container and returns its exit code,
+// stdout and stderr. A non-zero exit code is not an error. With WithReplicas
+// it returns an error; use ExecReplica.
+func (c *Container) Exec(ctx context.Context, cmd []string) (exitCode int, stdout, stderr string, err error) {
+	if c.replicas != nil {
+		return 0, "", "", errors.New("service: Exec is not supported with WithReplicas, use ExecReplica")
+	}
+	return c.current().Exec(ctx, cmd)
+}
+
+// CopyFileTo copies the file at hostPath into the service container. With
+// WithReplicas it copies it into every replica.
+func (c *Container) CopyFileTo(ctx context.Context, hostPath, containerPath string) error {
+	if c.replicas != nil {
+		return c.eachReplica(func(r *replica) error {
+			return r.base.CopyFileTo(ctx, hostPath, containerPath)
+		})
+	}
+	return c.current().CopyFileTo(ctx, hostPath, containerPath)
+}
+
+// CopyFileFrom copies a file out of the service container to hostPath. With
+// WithReplicas it returns an error, as the replicas' files may differ.
+func (c *Container) CopyFileFrom(ctx context.Context, containerPath, hostPath string) error {
+	if c.replicas != nil {
+		return errors.New("service: CopyFileFrom is not supported with WithReplicas")
+	}
+	return c.current().CopyFileFrom(ctx, containerPath, hostPath)
+}
+
+// Stop stops the service container without removing it. With WithReplicas
+// it stops every replica and the load balancer.
+func (c *Container) Stop(ctx context.Context) error {
+	if c.replicas != nil {
+		return c.eachContainer(func(b *container.Base) error { return b.Stop(ctx, nil) })
+	}
+	return c.current().Stop(ctx, nil)
+}
+
+// Start starts a container stopped with Stop. The host port is bound
+// explicitly, so URL stays the same. With WithReplicas it starts every
+// replica and the load balancer.
+func (c *Container) Start(ctx context.Context) error {
+	if c.replicas != nil {
+		return c.eachContainer(func(b *container.Base) error { return b.Start(ctx) })
+	}
+	return c.current().Start(ctx)
+}
+
+// Restart stops and starts the service container. With WithReplicas it
+// restarts every replica and the load balancer.
+func (c *Container) Restart(ctx context.Context) error {
+	if c.replicas != nil {
+		return c.eachContainer(func(b *container.Base) error { return b.Restart(ctx, nil) })
+	}
+	return c.current().Restart(ctx, nil)
+}
+
+// Pause freezes every process in the service container until Unpause. With
+// WithReplicas it pauses every replica and the load balancer.
+func (c *Container) Pause(ctx context.Context) error {
+	if c.replicas != nil {
+		return c.eachContainer(func(b *container.Base) error { return b.Pause(ctx) })
+	}
+	return c.current().Pause(ctx)
+}
+
+// Unpause resumes a service container frozen with Pause. With WithReplicas
+// it resumes every replica and the load balancer.
+func (c *Container) Unpause(ctx context.Context) error {
+	if c.replicas != nil {
+		return c.eachContainer(func(b *container.Base) error { return b.Unpause(ctx) })
+	}
+	return c.current().Unpause(ctx)
+}
+
+// Terminate stops and removes the container. With WithCoverage the service is
+// first stopped gracefully and its coverage merged into the profile; the
+// container is removed even if that fails.
 func (c *Container) Terminate(ctx context.Context) error {
-	return c.base.Terminate(ctx)
-}
\ No newline at end of file
+	if c.replicas != nil {
+		return c.terminateReplicas(ctx)
+	}
+	base, cfg := c.snapshot()
+
+	var errs []error
+	if cfg.coverProfile != "" {
+		if err := collectCoverage(ctx, base, cfg.coverProfile); err != nil {
+			errs = append(errs, fmt.Errorf("collect coverage: %w", err))
+		}
+	}
+	if err := base.Terminate(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,156 @@
+package service_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/dsvdev/testground/service"
+)
+
+func TestService_ImageWithPorts(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	defer cancel()
+
+	svc, err := service.New(ctx,
+		service.WithImage("nginx:1.27-alpine"),
+		service.WithPorts("80", "8081"),
+		service.WithWaitFor(wait.ForListeningPort("80/tcp")),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { svc.Terminate(context.Background()) })
+
+	if svc.URL() != svc.MappedURL("80") {
+		t.Errorf("URL() = %q, MappedURL(80) = %q, want the primary port", svc.URL(), svc.MappedURL("80"))
+	}
+	if svc.MappedURL("8081") == "" {
+		t.Error("MappedURL(8081) is empty for an exposed port")
+	}
+	if svc.MappedURL("9999") != "" {
+		t.Error("MappedURL(9999) is not empty for a port that was not exposed")
+	}
+
+	resp, err := http.Get(svc.URL())
+	if err != nil {
+		t.Fatalf("GET %s: %v", svc.URL(), err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET %s = %d, want 200", svc.URL(), resp.StatusCode)
+	}
+}
+
+func TestService_Replace(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 180*time.Second)
+	defer cancel()
+
+	svc, err := service.New(ctx,
+		service.WithImage("nginx:1.26-alpine"),
+		service.WithPort("80"),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { svc.Terminate(context.Background()) })
+
+	url := svc.URL()
+	if err := svc.Replace(ctx, service.WithImage("nginx:1.27-alpine")); err != nil {
+		t.Fatalf("Replace() error = %v", err)
+	}
+	if svc.URL() != url {
+		t.Errorf("URL() = %q after Replace, want %q", svc.URL(), url)
+	}
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s after Replace: %v", url, err)
+	}
+	resp.Body.Close()
+	if got := resp.Header.Get("Server"); !strings.HasPrefix(got, "nginx/1.27.") {
+		t.Errorf("Server header = %q, want the replacement nginx/1.27.x", got)
+	}
+}
+
+func TestService_Replicas(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 180*time.Second)
+	defer cancel()
+
+	svc, err := service.New(ctx,
+		service.WithImage("traefik/whoami:v1.10"),
+		service.WithPort("80"),
+		service.WithReplicas(2),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { svc.Terminate(context.Background()) })
+
+	if svc.Replicas() != 2 {
+		t.Fatalf("Replicas() = %d, want 2", svc.Replicas())
+	}
+	if svc.ReplicaURL(0) == svc.URL() || svc.ReplicaURL(0) == svc.ReplicaURL(1) {
+		t.Errorf("URL() = %q, ReplicaURL(0) = %q, ReplicaURL(1) = %q, want distinct", svc.URL(), svc.ReplicaURL(0), svc.ReplicaURL(1))
+	}
+
+	if got := hostnames(t, svc.URL(), 6); len(got) != 2 {
+		t.Errorf("balanced requests reached %v, want both replicas", got)
+	}
+
+	if err := svc.KillReplica(ctx, 0); err != nil {
+		t.Fatalf("KillReplica(0) error = %v", err)
+	}
+	if got := hostnames(t, svc.URL(), 4); len(got) != 1 {
+		t.Errorf("requests after KillReplica(0) reached %v, want only the remaining replica", got)
+	}
+	if err := svc.Healthy(ctx); err == nil {
+		t.Error("Healthy() error = nil with a killed replica, want error")
+	}
+
+	if err := svc.Stop(ctx); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if resp, err := http.Get(svc.URL()); err == nil {
+		resp.Body.Close()
+		t.Errorf("GET %s after Stop() succeeded, want the balancer down", svc.URL())
+	}
+	if err := svc.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := svc.Healthy(ctx); err != nil {
+		t.Errorf("Healthy() after Start() error = %v", err)
+	}
+	if got := hostnames(t, svc.URL(), 6); len(got) != 2 {
+		t.Errorf("requests after Start() reached %v, want both replicas", got)
+	}
+}
+
+// hostnames sends n requests to a traefik/whoami URL and returns the set of
+// hostnames that answered.
+func hostnames(t *testing.T, url string, n int) map[string]bool {
+	t.Helper()
+	seen := make(map[string]bool)
+	for range n {
+		resp, err := http.Get(url)
+		if err != nil {
+			t.Fatalf("GET %s: %v", url, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s = %d, want 200", url, resp.StatusCode)
+		}
+		for line := range strings.Lines(string(body)) {
+			if host, ok := strings.CutPrefix(line, "Hostname: "); ok {
+				seen[strings.TrimSpace(host)] = true
+			}
+		}
+	}
+	return seen
+}
@@ -0,0 +1,148 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/dsvdev/testground/internal/container"
+)
+
+// Backend selects the broker New starts. All backends speak the Kafka
+// protocol and support the whole Container API.
+type Backend int
+
+const (
+	// Zookeeper runs cp-kafka with a cp-zookeeper node: the classic
+	// deployment, two containers. It is the default.
+	Zookeeper Backend = iota
+	// KRaft runs a single cp-kafka node that is its own controller, without
+	// Zookeeper. It starts faster and is what Kafka 4 deploys.
+	KRaft
+	// Redpanda runs Redpanda in dev-container mode. It starts in a few
+	// seconds; the Kafka CLI tools are not in the image, use rpk with Exec.
+	Redpanda
+)
+
+func (b Backend) String() string {
+	switch b {
+	case Zookeeper:
+		return "Zookeeper"
+	case KRaft:
+		return "KRaft"
+	case Redpanda:
+		return "Redpanda"
+	}
+	return fmt.Sprintf("Backend(%d)", int(b))
+}
+
+// Default image versions of the backends, see WithVersion.
+const (
+	defaultConfluentVersion = "7.6.1"
+	defaultRedpandaVersion  = "v24.2.7"
+)
+
+// kraftClusterID identifies the KRaft cluster; any 16 bytes in URL-safe
+// base64 do.
+const kraftClusterID = "dGVzdGdyb3VuZC1rcmFmdA"
+
+// imageVersion returns the image tag of the backend.
+func (c config) imageVersion() string {
+	switch {
+	case c.version != "":
+		return c.version
+	case c.backend == Redpanda:
+		return defaultRedpandaVersion
+	default:
+		return defaultConfluentVersion
+	}
+}
+
+// newStandalone starts a broker of the KRaft or Redpanda backend, which needs
+// no other container.
+func newStandalone(ctx context.Context, cfg config) (*Container, error) {
+	var opts []container.Option
+	if cfg.reuseName != "" {
+		opts = append(opts, container.WithReuse(strings.ToLower(cfg.backend.String())+"-"+cfg.reuseName, container.ConfigHash(cfg)))
+	}
+
+	// Resolve a free host port so we can bake it into the advertised address
+	// before the container starts. A reused broker keeps the port it was
+	// created with and this one goes unused.
+	freePort, err := container.FreePort()
+	if err != nil {
+		return nil, fmt.Errorf("kafka: find free port: %w", err)
+	}
+
+	// Two listeners, as with Zookeeper: internal on 9092 for the external
+	// network, external on 29092 mapped to freePort on the host. Without
+	// WithNetwork nobody can reach the internal one, and it advertises
+	// localhost so the broker can still resolve itself.
+	internalHost := "localhost"
+	if cfg.networkName != "" {
+		internalHost = cfg.networkAlias
+	}
+	var req testcontainers.ContainerRequest
+	if cfg.backend == Redpanda {
+		req = redpandaRequest(cfg, internalHost, freePort)
+	} else {
+		req = kraftRequest(cfg, internalHost, freePort)
+	}
+	req.ExposedPorts = []string{fmt.Sprintf("%d:29092/tcp", freePort)}
+	if cfg.networkName != "" {
+		req.Networks = []string{cfg.networkName}
+		req.NetworkAliases = map[string][]string{
+			cfg.networkName: {cfg.networkAlias},
+		}
+	}
+
+	base, err := container.Start(ctx, req, "29092", opts...)
+	if err != nil {
+		return nil, fmt.Errorf("kafka: start %s broker: %w", cfg.backend, err)
+	}
+	return &Container{kafka: base, cfg: cfg}, nil
+}
+
+// kraftRequest describes a cp-kafka node acting as both broker and
+// controller. The controller listens on 9093 inside the container only.
+func kraftRequest(cfg config, internalHost string, hostPort int) testcontainers.ContainerRequest {
+	return testcontainers.ContainerRequest{
+		Image: fmt.Sprintf("confluentinc/cp-kafka:%s", cfg.imageVersion()),
+		Env: map[string]string{
+			"CLUSTER_ID":                                     kraftClusterID,
+			"KAFKA_NODE_ID":                                  "1",
+			"KAFKA_PROCESS_ROLES":                            "broker,controller",
+			"KAFKA_CONTROLLER_QUORUM_VOTERS":                 "1@localhost:9093",
+			"KAFKA_CONTROLLER_LISTENER_NAMES":                "CONTROLLER",
+			"KAFKA_LISTENERS":                                "PLAINTEXT://0.0.0.0:9092,PLAINTEXT_HOST://0.0.0.0:29092,CONTROLLER://0.0.0.0:9093",
+			"KAFKA_ADVERTISED_LISTENERS":                     fmt.Sprintf("PLAINTEXT://%s:9092,PLAINTEXT_HOST://localhost:%d", internalHost, hostPort),
+			"KAFKA_LISTENER_SECURITY_PROTOCOL_MAP":           "CONTROLLER:PLAINTEXT,PLAINTEXT:PLAINTEXT,PLAINTEXT_HOST:PLAINTEXT",
+			"KAFKA_INTER_BROKER_LISTENER_NAME":               "PLAINTEXT",
+			"KAFKA_OFFSETS_TOPIC_REPLICATION_FACTOR":         "1",
+			"KAFKA_DEFAULT_REPLICATION_FACTOR":               "1",
+			"KAFKA_MIN_INSYNC_REPLICAS":                      "1",
+			"KAFKA_TRANSACTION_STATE_LOG_REPLICATION_FACTOR": "1",
+			"KAFKA_TRANSACTION_STATE_LOG_MIN_ISR":            "1",
+		},
+		WaitingFor: wait.ForLog("Kafka Server started"),
+	}
+}
+
+// redpandaRequest describes a single Redpanda node. Dev-container mode turns
+// off fsync and enables topic auto-creation, like the Kafka defaults.
+func redpandaRequest(cfg config, internalHost string, hostPort int) testcontainers.ContainerRequest {
+	return testcontainers.ContainerRequest{
+		Image: fmt.Sprintf("docker.redpanda.com/redpandadata/redpanda:%s", cfg.imageVersion()),
+		Cmd: []string{
+			"redpanda", "start",
+			"--mode", "dev-container",
+			"--smp", "1",
+			"--kafka-addr", "internal://0.0.0.0:9092,external://0.0.0.0:29092",
+			"--advertise-kafka-addr", fmt.Sprintf("internal://%s:9092,external://localhost:%d", internalHost, hostPort),
+		},
+		WaitingFor: wait.ForLog("Successfully started Redpanda!"),
+	}
+}
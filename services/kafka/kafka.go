@@ -3,42 +3,84 @@ package kafka
 import (
 	"context"
 	"fmt"
-	"net"
+	"io"
+	"time"
 
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/wait"
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kgo"
 
 	"github.com/dsvdev/testground"
 	"github.com/dsvdev/testground/internal/container"
 )
 
-// Container manages a Zookeeper + Kafka pair connected via a private internal
-// network. Externally only kafka.Container is visible; callers interact with it
-// through BootstrapServers / NetworkBootstrapServers / Terminate.
+// Container manages a Kafka-compatible broker. With the default Zookeeper
+// backend it is a Zookeeper + Kafka pair connected via a private internal
+// network; with KRaft or Redpanda it is a single container. Externally only
+// kafka.Container is visible; callers interact with it through
+// BootstrapServers / NetworkBootstrapServers / Terminate.
 type Container struct {
+	// zookeeper and innerNet are nil unless the backend is Zookeeper.
 	zookeeper *container.Base
 	kafka     *container.Base
 	innerNet  *testground.Network
 	cfg       config
 }
 
-// New creates an internal Docker network, starts Zookeeper, then starts Kafka.
-// On any error the already-started resources are stopped in reverse order.
+// New starts the broker of the backend set with WithBackend. On any error the
+// already-started resources are stopped in reverse order.
 func New(ctx context.Context, opts ...Option) (*Container, error) {
 	cfg := defaultConfig()
 	for _, opt := range opts {
 		opt(&cfg)
 	}
 
+	var c *Container
+	var err error
+	switch cfg.backend {
+	case Zookeeper:
+		c, err = newZookeeper(ctx, cfg)
+	case KRaft, Redpanda:
+		c, err = newStandalone(ctx, cfg)
+	default:
+		err = fmt.Errorf("kafka: unknown backend %v", cfg.backend)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if c.kafka.Reused() {
+		if err := c.Reset(ctx); err != nil {
+			return nil, fmt.Errorf("kafka: reset reused broker: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// newZookeeper creates an internal Docker network, starts Zookeeper, then
+// starts Kafka.
+func newZookeeper(ctx context.Context, cfg config) (*Container, error) {
+	// In reuse mode every resource gets a stable name derived from the
+	// configuration, so the next run can find and attach to it.
+	var netOpts []testground.NetworkOption
+	var zkOpts, kafkaOpts []container.Option
+	if cfg.reuseName != "" {
+		hash := container.ConfigHash(cfg)
+		netOpts = append(netOpts, testground.WithReusableName(fmt.Sprintf("testground-kafka-%s-%s", cfg.reuseName, hash)))
+		zkOpts = append(zkOpts, container.WithReuse("zookeeper-"+cfg.reuseName, hash))
+		kafkaOpts = append(kafkaOpts, container.WithReuse("kafka-"+cfg.reuseName, hash))
+	}
+
 	// Step 1: internal network for Zookeeper↔Kafka communication.
-	innerNet, err := testground.NewNetwork(ctx)
+	innerNet, err := testground.NewNetwork(ctx, netOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("kafka: create internal network: %w", err)
 	}
 
 	// Step 2: Zookeeper.
 	zkReq := testcontainers.ContainerRequest{
-		Image:        fmt.Sprintf("confluentinc/cp-zookeeper:%s", cfg.version),
+		Image:        fmt.Sprintf("confluentinc/cp-zookeeper:%s", cfg.imageVersion()),
 		ExposedPorts: []string{"2181/tcp"},
 		Env: map[string]string{
 			"ZOOKEEPER_CLIENT_PORT": "2181",
@@ -51,15 +93,16 @@ func New(ctx context.Context, opts ...Option) (*Container, error) {
 		WaitingFor: wait.ForLog("binding to port"),
 	}
 
-	zkBase, err := container.Start(ctx, zkReq, "2181")
+	zkBase, err := container.Start(ctx, zkReq, "2181", zkOpts...)
 	if err != nil {
 		innerNet.Terminate(ctx) //nolint:errcheck
 		return nil, fmt.Errorf("kafka: start zookeeper: %w", err)
 	}
 
 	// Step 3: resolve a free host port so we can bake it into
-	// KAFKA_ADVERTISED_LISTENERS before the container starts.
-	freePort, err := getFreePort()
+	// KAFKA_ADVERTISED_LISTENERS before the container starts. A reused broker
+	// keeps the port it was created with and this one goes unused.
+	freePort, err := container.FreePort()
 	if err != nil {
 		zkBase.Terminate(ctx)   //nolint:errcheck
 		innerNet.Terminate(ctx) //nolint:errcheck
@@ -80,7 +123,7 @@ func New(ctx context.Context, opts ...Option) (*Container, error) {
 	}
 
 	kafkaReq := testcontainers.ContainerRequest{
-		Image:        fmt.Sprintf("confluentinc/cp-kafka:%s", cfg.version),
+		Image:        fmt.Sprintf("confluentinc/cp-kafka:%s", cfg.imageVersion()),
 		ExposedPorts: []string{fmt.Sprintf("%d:29092/tcp", freePort)},
 		Env: map[string]string{
 			"KAFKA_BROKER_ID":                                "1",
@@ -100,7 +143,7 @@ func New(ctx context.Context, opts ...Option) (*Container, error) {
 		WaitingFor:     wait.ForLog("started (kafka.server.KafkaServer)"),
 	}
 
-	kafkaBase, err := container.Start(ctx, kafkaReq, "29092")
+	kafkaBase, err := container.Start(ctx, kafkaReq, "29092", kafkaOpts...)
 	if err != nil {
 		zkBase.Terminate(ctx)   //nolint:errcheck
 		innerNet.Terminate(ctx) //nolint:errcheck
@@ -126,12 +169,154 @@ func (c *Container) NetworkBootstrapServers() string {
 	return fmt.Sprintf("%s:9092", c.cfg.networkAlias)
 }
 
+// ContainerID returns the Docker ID of the Kafka broker container, e.g. for
+// testground.Network.Disconnect.
+func (c *Container) ContainerID() string {
+	return c.kafka.ID()
+}
+
+// Logs returns the Kafka broker log produced so far. The caller must close
+// the returned reader.
+func (c *Container) Logs(ctx context.Context) (io.ReadCloser, error) {
+	return c.kafka.Logs(ctx)
+}
+
+// LogsSince returns the Kafka broker log produced since the Docker engine
+// time since. The caller must close the returned reader.
+func (c *Container) LogsSince(ctx context.Context, since time.Time) (io.ReadCloser, error) {
+	return c.kafka.LogsSince(ctx, since)
+}
+
+// ZookeeperLogs returns the Zookeeper log produced so far. The caller must
+// close the returned reader. It fails unless the backend is Zookeeper.
+func (c *Container) ZookeeperLogs(ctx context.Context) (io.ReadCloser, error) {
+	if c.zookeeper == nil {
+		return nil, fmt.Errorf("kafka: no Zookeeper with the %s backend", c.cfg.backend)
+	}
+	return c.zookeeper.Logs(ctx)
+}
+
+// Exec runs cmd inside the Kafka broker container and returns its exit code,
+// stdout and stderr. A non-zero exit code is not an error.
+func (c *Container) Exec(ctx context.Context, cmd []string) (exitCode int, stdout, stderr string, err error) {
+	return c.kafka.Exec(ctx, cmd)
+}
+
+// CopyFileTo copies the file at hostPath into the Kafka broker container.
+func (c *Container) CopyFileTo(ctx context.Context, hostPath, containerPath string) error {
+	return c.kafka.CopyFileTo(ctx, hostPath, containerPath)
+}
+
+// CopyFileFrom copies a file out of the Kafka broker container to hostPath.
+func (c *Container) CopyFileFrom(ctx context.Context, containerPath, hostPath string) error {
+	return c.kafka.CopyFileFrom(ctx, containerPath, hostPath)
+}
+
+// Stop stops the Kafka broker without removing it; Zookeeper, if any, keeps
+// running.
+// Topics and committed offsets are kept. Clients fail until Start.
+func (c *Container) Stop(ctx context.Context) error {
+	return c.kafka.Stop(ctx, nil)
+}
+
+// Start starts a broker stopped with Stop and waits until it serves metadata
+// requests again. The host port is bound explicitly, so BootstrapServers and
+// the advertised listeners stay valid and existing clients reconnect.
+func (c *Container) Start(ctx context.Context) error {
+	if err := c.kafka.Start(ctx); err != nil {
+		return fmt.Errorf("kafka: %w", err)
+	}
+	// After a restart the wait strategy matches the log line of the previous
+	// start, so it cannot be relied upon.
+	if err := testground.WaitHealthy(ctx, c); err != nil {
+		return fmt.Errorf("kafka: wait for broker: %w", err)
+	}
+	return nil
+}
+
+// Restart stops and starts the broker, see Stop and Start.
+func (c *Container) Restart(ctx context.Context) error {
+	if err := c.Stop(ctx); err != nil {
+		return err
+	}
+	return c.Start(ctx)
+}
+
+// Pause freezes the broker: connections stay open but requests hang until
+// Unpause.
+func (c *Container) Pause(ctx context.Context) error {
+	return c.kafka.Pause(ctx)
+}
+
+// Unpause resumes a broker frozen with Pause.
+func (c *Container) Unpause(ctx context.Context) error {
+	return c.kafka.Unpause(ctx)
+}
+
+// Healthy reports whether the broker answers a metadata request.
+func (c *Container) Healthy(ctx context.Context) error {
+	client, err := kgo.NewClient(kgo.SeedBrokers(c.BootstrapServers()))
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	pingCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	return client.Ping(pingCtx)
+}
+
+// Reset deletes every topic except Kafka's internal ones, and every consumer
+// group. New calls Reset automatically when WithReuse attaches to a broker
+// from an earlier run.
+func (c *Container) Reset(ctx context.Context) error {
+	client, err := kgo.NewClient(kgo.SeedBrokers(c.BootstrapServers()))
+	if err != nil {
+		return fmt.Errorf("reset: connect: %w", err)
+	}
+	defer client.Close()
+	admin := kadm.NewClient(client)
+
+	topics, err := admin.ListTopics(ctx)
+	if err != nil {
+		return fmt.Errorf("reset: list topics: %w", err)
+	}
+	if names := topics.Names(); len(names) > 0 {
+		res, err := admin.DeleteTopics(ctx, names...)
+		if err != nil {
+			return fmt.Errorf("reset: delete topics: %w", err)
+		}
+		if err := res.Error(); err != nil {
+			return fmt.Errorf("reset: delete topics: %w", err)
+		}
+	}
+
+	groups, err := admin.ListGroups(ctx)
+	if err != nil {
+		return fmt.Errorf("reset: list groups: %w", err)
+	}
+	if names := groups.Groups(); len(names) > 0 {
+		res, err := admin.DeleteGroups(ctx, names...)
+		if err != nil {
+			return fmt.Errorf("reset: delete groups: %w", err)
+		}
+		if err := res.Error(); err != nil {
+			return fmt.Errorf("reset: delete groups: %w", err)
+		}
+	}
+	return nil
+}
+
 // Terminate stops Kafka, then Zookeeper, then the internal network.
+// With WithReuse all three are left running for the next run.
 func (c *Container) Terminate(ctx context.Context) error {
 	var first error
 	if err := c.kafka.Terminate(ctx); err != nil {
 		first = err
 	}
+	if c.zookeeper == nil {
+		return first
+	}
 	if err := c.zookeeper.Terminate(ctx); err != nil && first == nil {
 		first = err
 	}
@@ -140,14 +325,3 @@ func (c *Container) Terminate(ctx context.Context) error {
 	}
 	return first
 }
-
-// getFreePort asks the OS for an available TCP port and immediately releases it.
-// There is a small TOCTOU window, but in practice it is negligible for tests.
-func getFreePort() (int, error) {
-	l, err := net.Listen("tcp", ":0")
-	if err != nil {
-		return 0, err
-	}
-	defer l.Close()
-	return l.Addr().(*net.TCPAddr).Port, nil
-}
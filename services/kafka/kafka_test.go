@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/twmb/franz-go/pkg/kadm"
 	"github.com/twmb/franz-go/pkg/kgo"
 
 	"github.com/dsvdev/testground"
@@ -18,7 +19,7 @@ func TestKafka_Preconditions(t *testing.T) {
 	if err != nil {
 		t.Fatalf("start kafka: %v", err)
 	}
-	defer kc.Terminate(ctx)
+	t.Cleanup(func() { kc.Terminate(ctx) })
 
 	testground.Apply(t,
 		kc.CreateTopic("events", kafkasvc.WithPartitions(1)),
@@ -32,6 +33,41 @@ func TestKafka_Preconditions(t *testing.T) {
 	kc.AssertHasMessageContaining(t, "events", `"id": 1`, 2)
 }
 
+func TestKafka_CreateTopicRevert(t *testing.T) {
+	ctx := context.Background()
+
+	kc, err := kafkasvc.New(ctx)
+	if err != nil {
+		t.Fatalf("start kafka: %v", err)
+	}
+	t.Cleanup(func() { kc.Terminate(ctx) })
+
+	listTopics := func() kadm.TopicDetails {
+		t.Helper()
+		client, err := kgo.NewClient(kgo.SeedBrokers(kc.BootstrapServers()))
+		if err != nil {
+			t.Fatalf("connect: %v", err)
+		}
+		defer client.Close()
+		topics, err := kadm.NewClient(client).ListTopics(ctx)
+		if err != nil {
+			t.Fatalf("list topics: %v", err)
+		}
+		return topics
+	}
+
+	t.Run("inner", func(t *testing.T) {
+		testground.Apply(t, kc.CreateTopic("temporary"))
+		if !listTopics().Has("temporary") {
+			t.Fatal("topic was not created")
+		}
+	})
+
+	if listTopics().Has("temporary") {
+		t.Error("topic was not deleted after the test finished")
+	}
+}
+
 func TestKafka_Smoke(t *testing.T) {
 	ctx := context.Background()
 
@@ -39,7 +75,7 @@ func TestKafka_Smoke(t *testing.T) {
 	if err != nil {
 		t.Fatalf("start kafka: %v", err)
 	}
-	defer kc.Terminate(ctx)
+	t.Cleanup(func() { kc.Terminate(ctx) })
 
 	const topic = "smoke-topic"
 	const want = "hello kafka"
@@ -115,3 +151,70 @@ func TestKafka_WithNetwork(t *testing.T) {
 		t.Errorf("expected kafka:9092, got %q", got)
 	}
 }
+
+func TestKafka_Restart(t *testing.T) {
+	ctx := context.Background()
+
+	kc, err := kafkasvc.New(ctx)
+	if err != nil {
+		t.Fatalf("start kafka: %v", err)
+	}
+	t.Cleanup(func() { kc.Terminate(ctx) })
+
+	testground.Apply(t,
+		kc.CreateTopic("restart"),
+		kc.Publish("restart", []byte("before")),
+	)
+	servers := kc.BootstrapServers()
+
+	// A client created before the restart must reconnect on its own.
+	client, err := kgo.NewClient(kgo.SeedBrokers(servers))
+	if err != nil {
+		t.Fatalf("create client: %v", err)
+	}
+	defer client.Close()
+
+	if err := kc.Restart(ctx); err != nil {
+		t.Fatalf("Restart() error = %v", err)
+	}
+	if got := kc.BootstrapServers(); got != servers {
+		t.Errorf("BootstrapServers() = %q after restart, want %q", got, servers)
+	}
+
+	produceCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	if err := client.ProduceSync(produceCtx, &kgo.Record{Topic: "restart", Value: []byte("after")}).FirstErr(); err != nil {
+		t.Fatalf("produce after restart: %v", err)
+	}
+
+	kc.AssertMessageCount(t, "restart", 2)
+}
+
+func TestKafka_Backends(t *testing.T) {
+	for _, backend := range []kafkasvc.Backend{kafkasvc.KRaft, kafkasvc.Redpanda} {
+		t.Run(backend.String(), func(t *testing.T) {
+			ctx := context.Background()
+
+			kc, err := kafkasvc.New(ctx, kafkasvc.WithBackend(backend))
+			if err != nil {
+				t.Fatalf("start %s: %v", backend, err)
+			}
+			t.Cleanup(func() { kc.Terminate(ctx) })
+
+			testground.Apply(t,
+				kc.CreateTopic("events", kafkasvc.WithPartitions(3)),
+				kc.Publish("events", []byte(`{"id": 1}`)),
+				kc.Publish("events", []byte(`{"id": 2}`)),
+			)
+			kc.AssertMessageCount(t, "events", 2)
+			kc.AssertHasMessage(t, "events", []byte(`{"id": 2}`))
+
+			if _, err := kc.ZookeeperLogs(ctx); err == nil {
+				t.Error("ZookeeperLogs() error = nil, want error without Zookeeper")
+			}
+			if err := kc.Reset(ctx); err != nil {
+				t.Fatalf("Reset() error = %v", err)
+			}
+		})
+	}
+}
@@ -6,19 +6,21 @@ type config struct {
 	version      string
 	networkName  string
 	networkAlias string
+	reuseName    string
+	backend      Backend
 }
 
 func defaultConfig() config {
 	return config{
-		version:      "7.6.1",
 		networkAlias: "kafka",
 	}
 }
 
 type Option func(*config)
 
-// WithVersion sets the Docker image version for both cp-zookeeper and cp-kafka.
-// Default: "7.9".
+// WithVersion sets the Docker image version: the cp-zookeeper and cp-kafka
+// tag for the Zookeeper and KRaft backends, the redpanda tag for Redpanda.
+// Default: "7.6.1", or "v24.2.7" for Redpanda.
 func WithVersion(v string) Option {
 	return func(c *config) {
 		c.version = v
@@ -40,3 +42,26 @@ func WithNetworkAlias(alias string) Option {
 		c.networkAlias = alias
 	}
 }
+
+// WithBackend selects the broker: Zookeeper (default) for the classic
+// deployment, KRaft for a single Kafka node without Zookeeper, or Redpanda
+// for the fastest startup. The Container API is the same for all of them.
+func WithBackend(b Backend) Option {
+	return func(c *config) {
+		c.backend = b
+	}
+}
+
+// WithReuse keeps Zookeeper, Kafka and their internal network running after
+// Terminate and attaches to them on the next run instead of starting new ones,
+// as long as the configuration is unchanged. All topics and consumer groups
+// are deleted on attach, see Reset.
+// With the KRaft and Redpanda backends there is only the broker to keep.
+//
+// Cross-run reuse requires TESTCONTAINERS_RYUK_DISABLED=true; otherwise the
+// testcontainers reaper removes the containers when the test binary exits.
+func WithReuse(name string) Option {
+	return func(c *config) {
+		c.reuseName = name
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/twmb/franz-go/pkg/kadm"
@@ -44,19 +45,28 @@ type createTopicPrecondition struct {
 	container *Container
 	topic     string
 	cfg       topicConfig
+
+	// created records, per test the Precondition was applied in, that Apply
+	// created the topic, so a value can be reused and applied in Parallel.
+	mu      sync.Mutex
+	created map[*testing.T]bool
 }
 
 // CreateTopic returns a Precondition that creates the given topic.
 // If the topic already exists the call is a no-op.
+//
+// The returned Precondition is a testground.Reverter: when applied through
+// testground.Apply the topic is deleted in t.Cleanup, unless it already
+// existed before Apply.
 func (c *Container) CreateTopic(topic string, opts ...TopicOption) testground.Precondition {
 	cfg := defaultTopicConfig()
 	for _, opt := range opts {
 		opt(&cfg)
 	}
-	return &createTopicPrecondition{container: c, topic: topic, cfg: cfg}
+	return &createTopicPrecondition{container: c, topic: topic, cfg: cfg, created: make(map[*testing.T]bool)}
 }
 
-func (p *createTopicPrecondition) Apply(ctx context.Context, _ *testing.T) error {
+func (p *createTopicPrecondition) Apply(ctx context.Context, t *testing.T) error {
 	client, err := kgo.NewClient(kgo.SeedBrokers(p.container.BootstrapServers()))
 	if err != nil {
 		return fmt.Errorf("create topic %q: connect: %w", p.topic, err)
@@ -73,6 +83,40 @@ func (p *createTopicPrecondition) Apply(ctx context.Context, _ *testing.T) error
 		if r.Err != nil && !errors.Is(r.Err, kerr.TopicAlreadyExists) {
 			return fmt.Errorf("create topic %q: %w", r.Topic, r.Err)
 		}
+		if r.Err == nil {
+			p.mu.Lock()
+			p.created[t] = true
+			p.mu.Unlock()
+		}
+	}
+	return nil
+}
+
+func (p *createTopicPrecondition) Revert(ctx context.Context, t *testing.T) error {
+	p.mu.Lock()
+	created := p.created[t]
+	delete(p.created, t)
+	p.mu.Unlock()
+	if !created {
+		return nil
+	}
+
+	client, err := kgo.NewClient(kgo.SeedBrokers(p.container.BootstrapServers()))
+	if err != nil {
+		return fmt.Errorf("delete topic %q: connect: %w", p.topic, err)
+	}
+	defer client.Close()
+
+	admin := kadm.NewClient(client)
+	res, err := admin.DeleteTopics(ctx, p.topic)
+	if err != nil {
+		return fmt.Errorf("delete topic %q: %w", p.topic, err)
+	}
+
+	for _, r := range res {
+		if r.Err != nil && !errors.Is(r.Err, kerr.UnknownTopicOrPartition) {
+			return fmt.Errorf("delete topic %q: %w", r.Topic, r.Err)
+		}
 	}
 	return nil
 }
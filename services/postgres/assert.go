@@ -0,0 +1,383 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// maxShownRows bounds the rows printed when an assertion fails.
+const maxShownRows = 20
+
+// check runs an assertion once. It returns a non-empty failure message if the
+// assertion does not hold, and an error if it could not be evaluated.
+type check func(ctx context.Context, pool *pgxpool.Pool) (failure string, err error)
+
+// AssertRowCount fails the test if the number of rows in table matching the
+// where clause is not n. An empty where counts every row. args are bound to
+// placeholders in where.
+//
+//	pg.AssertRowCount(t, "orders", "status = $1", 2, "paid")
+func (c *Container) AssertRowCount(t *testing.T, table, where string, n int, args ...any) {
+	t.Helper()
+	c.assert(t, "AssertRowCount", rowCount(table, where, n, args))
+}
+
+// AssertRowExists fails the test if no row in table has all the given
+// column values. A nil value matches NULL.
+//
+//	pg.AssertRowExists(t, "users", map[string]any{"email": "alice@example.com", "deleted_at": nil})
+func (c *Container) AssertRowExists(t *testing.T, table string, values map[string]any) {
+	t.Helper()
+	c.assert(t, "AssertRowExists", rowExists(table, values))
+}
+
+// AssertQueryReturns fails the test unless query returns exactly the expected
+// rows, in order. Only the columns named in expected are compared, so
+// SELECT * works. Integers and floats compare by value regardless of their
+// Go type, and UUIDs compare to their string form.
+//
+//	pg.AssertQueryReturns(t, "SELECT name FROM users ORDER BY id", []map[string]any{
+//		{"name": "Alice"},
+//		{"name": "Bob"},
+//	})
+func (c *Container) AssertQueryReturns(t *testing.T, query string, expected []map[string]any, args ...any) {
+	t.Helper()
+	c.assert(t, "AssertQueryReturns", queryReturns(query, expected, args))
+}
+
+// Eventually returns the row assertions in a form that polls every interval
+// until they hold, and fails the test only if they still do not after
+// timeout. Use it for data written asynchronously, e.g. by a consumer.
+//
+//	pg.Eventually(5*time.Second, 100*time.Millisecond).AssertRowCount(t, "events", "", 3)
+func (c *Container) Eventually(timeout, interval time.Duration) *Eventually {
+	return &Eventually{c: c, timeout: timeout, interval: interval}
+}
+
+// Eventually runs row assertions until they hold or a timeout expires. It is
+// returned by Container.Eventually.
+type Eventually struct {
+	c        *Container
+	timeout  time.Duration
+	interval time.Duration
+}
+
+// AssertRowCount is like Container.AssertRowCount, but polls.
+func (e *Eventually) AssertRowCount(t *testing.T, table, where string, n int, args ...any) {
+	t.Helper()
+	e.assert(t, "AssertRowCount", rowCount(table, where, n, args))
+}
+
+// AssertRowExists is like Container.AssertRowExists, but polls.
+func (e *Eventually) AssertRowExists(t *testing.T, table string, values map[string]any) {
+	t.Helper()
+	e.assert(t, "AssertRowExists", rowExists(table, values))
+}
+
+// AssertQueryReturns is like Container.AssertQueryReturns, but polls.
+func (e *Eventually) AssertQueryReturns(t *testing.T, query string, expected []map[string]any, args ...any) {
+	t.Helper()
+	e.assert(t, "AssertQueryReturns", queryReturns(query, expected, args))
+}
+
+func (c *Container) assert(t *testing.T, name string, chk check) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	pool, err := c.Pool(ctx)
+	if err != nil {
+		t.Fatalf("%s: connect: %v", name, err)
+	}
+	failure, err := chk(ctx, pool)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	if failure != "" {
+		t.Fatalf("%s: %s", name, failure)
+	}
+}
+
+func (e *Eventually) assert(t *testing.T, name string, chk check) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	// Errors are retried as well: the table may not exist yet.
+	var failure string
+	for {
+		pool, err := e.c.Pool(ctx)
+		if err == nil {
+			failure, err = chk(ctx, pool)
+		}
+		if err != nil {
+			failure = err.Error()
+		}
+		if failure == "" {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("%s: still failing after %s: %s", name, e.timeout, failure)
+		case <-time.After(e.interval):
+		}
+	}
+}
+
+func rowCount(table, where string, n int, args []any) check {
+	return func(ctx context.Context, pool *pgxpool.Pool) (string, error) {
+		from := identifier(table)
+		if where != "" {
+			from += " WHERE " + where
+		}
+		var got int
+		if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM "+from, args...).Scan(&got); err != nil {
+			return "", err
+		}
+		if got == n {
+			return "", nil
+		}
+		cols, rows, err := queryRows(ctx, pool, fmt.Sprintf("SELECT * FROM %s LIMIT %d", from, maxShownRows), args...)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("expected %d row(s) in %s, got %d\n%s", n, describeTable(table, where), got, formatRows(cols, rows, got)), nil
+	}
+}
+
+func rowExists(table string, values map[string]any) check {
+	return func(ctx context.Context, pool *pgxpool.Pool) (string, error) {
+		cols := slices.Sorted(maps.Keys(values))
+		var conds []string
+		var args []any
+		for _, col := range cols {
+			if values[col] == nil {
+				conds = append(conds, pgx.Identifier{col}.Sanitize()+" IS NULL")
+				continue
+			}
+			args = append(args, values[col])
+			conds = append(conds, fmt.Sprintf("%s = $%d", pgx.Identifier{col}.Sanitize(), len(args)))
+		}
+		where := "true"
+		if len(conds) > 0 {
+			where = strings.Join(conds, " AND ")
+		}
+
+		var exists bool
+		if err := pool.QueryRow(ctx, fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE %s)", identifier(table), where), args...).Scan(&exists); err != nil {
+			return "", err
+		}
+		if exists {
+			return "", nil
+		}
+
+		var total int
+		if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM "+identifier(table)).Scan(&total); err != nil {
+			return "", err
+		}
+		_, rows, err := queryRows(ctx, pool, fmt.Sprintf("SELECT * FROM %s LIMIT %d", identifier(table), maxShownRows))
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("no row in %s with %s\n%s", table, formatRow(cols, values), formatRows(cols, rows, total)), nil
+	}
+}
+
+func queryReturns(query string, expected []map[string]any, args []any) check {
+	return func(ctx context.Context, pool *pgxpool.Pool) (string, error) {
+		cols, rows, err := queryRows(ctx, pool, query, args...)
+		if err != nil {
+			return "", err
+		}
+
+		// Compare and show the columns the expectation names.
+		if len(expected) > 0 {
+			names := make(map[string]bool)
+			for _, row := range expected {
+				for col := range row {
+					names[col] = true
+				}
+			}
+			cols = slices.Sorted(maps.Keys(names))
+		}
+
+		equal := len(rows) == len(expected)
+		for i := 0; equal && i < len(rows); i++ {
+			equal = rowsEqual(cols, expected[i], rows[i])
+		}
+		if equal {
+			return "", nil
+		}
+		return fmt.Sprintf("expected %d row(s), got %d (- expected, + actual)\n%s",
+			len(expected), len(rows), diffRows(cols, expected, rows)), nil
+	}
+}
+
+// queryRows runs query and returns its column names and rows.
+func queryRows(ctx context.Context, pool *pgxpool.Pool, query string, args ...any) ([]string, []map[string]any, error) {
+	rows, err := pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	var cols []string
+	for _, f := range rows.FieldDescriptions() {
+		cols = append(cols, f.Name)
+	}
+	result, err := pgx.CollectRows(rows, pgx.RowToMap)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cols, result, nil
+}
+
+// identifier quotes a possibly schema-qualified table name.
+func identifier(table string) string {
+	return pgx.Identifier(strings.Split(table, ".")).Sanitize()
+}
+
+func describeTable(table, where string) string {
+	if where == "" {
+		return table
+	}
+	return fmt.Sprintf("%s WHERE %s", table, where)
+}
+
+// rowsEqual reports whether want and got agree on every column in cols.
+func rowsEqual(cols []string, want, got map[string]any) bool {
+	for _, col := range cols {
+		if _, ok := got[col]; !ok {
+			return false
+		}
+		if !valuesEqual(want[col], got[col]) {
+			return false
+		}
+	}
+	return true
+}
+
+// valuesEqual compares an expected value written in a test with a value
+// scanned by pgx.
+func valuesEqual(want, got any) bool {
+	want, got = normalize(want), normalize(got)
+	switch w := want.(type) {
+	case int64:
+		if g, ok := got.(float64); ok {
+			return float64(w) == g
+		}
+	case float64:
+		if g, ok := got.(int64); ok {
+			return w == float64(g)
+		}
+	case time.Time:
+		if g, ok := got.(time.Time); ok {
+			return w.Equal(g)
+		}
+	}
+	return reflect.DeepEqual(want, got)
+}
+
+// normalize converts the many Go types of one SQL value to a single one.
+func normalize(v any) any {
+	switch x := v.(type) {
+	case int:
+		return int64(x)
+	case int8:
+		return int64(x)
+	case int16:
+		return int64(x)
+	case int32:
+		return int64(x)
+	case uint8:
+		return int64(x)
+	case uint16:
+		return int64(x)
+	case uint32:
+		return int64(x)
+	case float32:
+		return float64(x)
+	case [16]byte:
+		return pgtype.UUID{Bytes: x, Valid: true}.String()
+	case pgtype.Numeric:
+		if f, err := x.Float64Value(); err == nil && f.Valid {
+			return f.Float64
+		}
+	}
+	return v
+}
+
+// formatRows prints rows, noting when only the first of total rows are shown.
+func formatRows(cols []string, rows []map[string]any, total int) string {
+	if len(rows) == 0 {
+		return "  (no rows)"
+	}
+	var sb strings.Builder
+	for i, row := range rows {
+		fmt.Fprintf(&sb, "  [%d] %s\n", i, formatRow(cols, row))
+	}
+	if total > len(rows) {
+		fmt.Fprintf(&sb, "  ... %d more\n", total-len(rows))
+	}
+	return sb.String()
+}
+
+// diffRows prints expected and actual rows side by side: rows that match
+// once, differing rows as a "-" expected and a "+" actual line.
+func diffRows(cols []string, expected, actual []map[string]any) string {
+	var sb strings.Builder
+	for i := range max(len(expected), len(actual)) {
+		switch {
+		case i >= len(expected):
+			fmt.Fprintf(&sb, "+ [%d] %s\n", i, formatRow(cols, actual[i]))
+		case i >= len(actual):
+			fmt.Fprintf(&sb, "- [%d] %s\n", i, formatRow(cols, expected[i]))
+		case rowsEqual(cols, expected[i], actual[i]):
+			fmt.Fprintf(&sb, "  [%d] %s\n", i, formatRow(cols, actual[i]))
+		default:
+			fmt.Fprintf(&sb, "- [%d] %s\n", i, formatRow(cols, expected[i]))
+			fmt.Fprintf(&sb, "+ [%d] %s\n", i, formatRow(cols, actual[i]))
+		}
+	}
+	if sb.Len() == 0 {
+		return "  (no rows)"
+	}
+	return sb.String()
+}
+
+// formatRow prints the cols of row as {col: value, ...}.
+func formatRow(cols []string, row map[string]any) string {
+	fields := make([]string, 0, len(cols))
+	for _, col := range cols {
+		v, ok := row[col]
+		if !ok {
+			fields = append(fields, col+": (missing)")
+			continue
+		}
+		fields = append(fields, col+": "+formatValue(v))
+	}
+	return "{" + strings.Join(fields, ", ") + "}"
+}
+
+func formatValue(v any) string {
+	switch x := normalize(v).(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return fmt.Sprintf("%q", x)
+	case []byte:
+		return fmt.Sprintf("%q", x)
+	case time.Time:
+		return x.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(x)
+	}
+}
@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestValuesEqual(t *testing.T) {
+	now := time.Now()
+	uuid := [16]byte{0x12, 0x34}
+	tests := []struct {
+		name      string
+		want, got any
+		equal     bool
+	}{
+		{"int and int64", 1, int64(1), true},
+		{"int and int32", 7, int32(7), true},
+		{"int and float64", 2, float64(2), true},
+		{"different ints", 1, int64(2), false},
+		{"strings", "Alice", "Alice", true},
+		{"string and int", "1", int64(1), false},
+		{"nil", nil, nil, true},
+		{"nil and value", nil, "x", false},
+		{"time in another zone", now, now.UTC(), true},
+		{"uuid", "12340000-0000-0000-0000-000000000000", uuid, true},
+		{"numeric", 1.5, pgtype.Numeric{Int: big.NewInt(15), Exp: -1, Valid: true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := valuesEqual(tt.want, tt.got); got != tt.equal {
+				t.Errorf("valuesEqual(%#v, %#v) = %v, want %v", tt.want, tt.got, got, tt.equal)
+			}
+		})
+	}
+}
+
+func TestDiffRows(t *testing.T) {
+	cols := []string{"id", "name"}
+	expected := []map[string]any{
+		{"id": 1, "name": "Alice"},
+		{"id": 2, "name": "Bob"},
+	}
+	actual := []map[string]any{
+		{"id": int64(1), "name": "Alice", "email": nil},
+		{"id": int64(2), "name": "Robert", "email": nil},
+		{"id": int64(3), "name": nil, "email": nil},
+	}
+
+	want := `  [0] {id: 1, name: "Alice"}
+- [1] {id: 2, name: "Bob"}
++ [1] {id: 2, name: "Robert"}
++ [2] {id: 3, name: NULL}
+`
+	if got := diffRows(cols, expected, actual); got != want {
+		t.Errorf("diffRows() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFormatRows_Truncated(t *testing.T) {
+	rows := []map[string]any{{"id": 1}}
+	want := "  [0] {id: 1}\n  ... 4 more\n"
+	if got := formatRows([]string{"id"}, rows, 5); got != want {
+		t.Errorf("formatRows() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,472 @@
+package postgres
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/dsvdev/testground/internal/container"
+)
+
+const (
+	// captureApp is the application_name of the recorder's own connection,
+	// whose statements are not recorded.
+	captureApp = "testground-capture"
+	// capturePrefix is the log_line_prefix while a capture is active. It
+	// carries the application name, so statements can be attributed.
+	capturePrefix = "tgq|%a|"
+)
+
+// logEntry matches a statement logged by log_min_duration_statement.
+var logEntry = regexp.MustCompile(`^tgq\|(.*?)\|LOG:  duration: ([0-9.]+) ms  (statement|execute [^:]*|parse [^:]*|bind [^:]*): (.*)$`)
+
+// Query is a statement the server ran during a capture.
+type Query struct {
+	// SQL is the statement text. Parameters of prepared statements appear
+	// as placeholders such as $1.
+	SQL string
+	// Duration is the execution time measured by the server.
+	Duration time.Duration
+	// Application is the application_name of the client, if it set one.
+	Application string
+}
+
+// QueryRecorder records the statements the server runs, returned by
+// CaptureQueries.
+type QueryRecorder struct {
+	c    *Container
+	conn *pgx.Conn
+	id   string
+	seq  int
+
+	// The server log is read incrementally: since is the timestamp of the
+	// last line read and atSince the number of lines read with it, which
+	// the next read returns again.
+	log     *capturedLog
+	since   time.Time
+	atSince int
+}
+
+// CaptureQueries records every statement the server runs from now until the
+// end of the test, from any client: the service under test as well as test
+// code. It enables statement logging with log_min_duration_statement = 0
+// and reads the statements back from the server log; the setting is reset
+// in t.Cleanup. Captures may overlap, e.g. in parallel tests, but each sees
+// the statements of all clients.
+func (c *Container) CaptureQueries(t *testing.T) *QueryRecorder {
+	t.Helper()
+	ctx := context.Background()
+
+	r, err := c.startCapture(ctx)
+	if err != nil {
+		t.Fatalf("postgres: capture queries: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := r.stop(context.Background()); err != nil {
+			t.Logf("warning: postgres: stop capturing queries: %v", err)
+		}
+	})
+	return r
+}
+
+func (c *Container) startCapture(ctx context.Context) (*QueryRecorder, error) {
+	// The recorder stays out of the database, so it neither blocks nor gets
+	// terminated by Snapshot and NewIsolatedDB, which copy it.
+	conn, err := c.appConn(ctx, c.maintenanceDatabase(), captureApp)
+	if err != nil {
+		return nil, fmt.Errorf("connect: %w", err)
+	}
+	id := strings.ToLower(rand.Text()[:12])
+	r := &QueryRecorder{c: c, conn: conn, id: id, log: newCapturedLog(markerPrefix(id))}
+	// Everything logged before the start marker is ignored, so the first
+	// read can start from now.
+	if r.since, err = container.EngineTime(ctx); err != nil {
+		conn.Close(ctx)
+		return nil, err
+	}
+
+	c.captureMu.Lock()
+	defer c.captureMu.Unlock()
+	if c.captures == 0 {
+		// ALTER SYSTEM cannot run in a transaction, so the statements are
+		// sent one by one rather than as a single multi-statement string.
+		for _, sql := range []string{
+			"ALTER SYSTEM SET log_min_duration_statement = 0",
+			"ALTER SYSTEM SET log_line_prefix = '" + capturePrefix + "'",
+			"SELECT pg_reload_conf()",
+		} {
+			if _, err := conn.Exec(ctx, sql); err != nil {
+				conn.Close(ctx)
+				return nil, fmt.Errorf("enable statement logging: %w", err)
+			}
+		}
+	}
+	c.captures++
+
+	// The marker only shows up once this backend has reloaded the settings,
+	// which happens before it runs the next statement.
+	if err := r.mark(ctx, "start"); err != nil {
+		r.stop(ctx) //nolint:errcheck
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *QueryRecorder) stop(ctx context.Context) error {
+	defer r.conn.Close(ctx)
+
+	r.c.captureMu.Lock()
+	defer r.c.captureMu.Unlock()
+	r.c.captures--
+	if r.c.captures > 0 {
+		return nil
+	}
+	return disableStatementLogging(ctx, r.conn)
+}
+
+// resetStatementLogging disables the statement logging of CaptureQueries
+// unless a capture is active.
+func (c *Container) resetStatementLogging(ctx context.Context) error {
+	c.captureMu.Lock()
+	defer c.captureMu.Unlock()
+	if c.captures > 0 {
+		return nil
+	}
+	conn, err := c.maintenanceConn(ctx)
+	if err != nil {
+		return fmt.Errorf("reset statement logging: connect: %w", err)
+	}
+	defer conn.Close(ctx)
+	return disableStatementLogging(ctx, conn)
+}
+
+// disableStatementLogging resets the settings CaptureQueries changes with
+// ALTER SYSTEM.
+func disableStatementLogging(ctx context.Context, conn *pgx.Conn) error {
+	for _, sql := range []string{
+		"ALTER SYSTEM RESET log_min_duration_statement",
+		"ALTER SYSTEM RESET log_line_prefix",
+		"SELECT pg_reload_conf()",
+	} {
+		if _, err := conn.Exec(ctx, sql); err != nil {
+			return fmt.Errorf("reset statement logging: %w", err)
+		}
+	}
+	return nil
+}
+
+// genericPlanVersion is the first server version whose EXPLAIN supports the
+// GENERIC_PLAN option, which plans statements with $n placeholders.
+const genericPlanVersion = 160000
+
+// requireGenericPlan returns an error naming the caller if the server is
+// older than PostgreSQL 16.
+func (r *QueryRecorder) requireGenericPlan(ctx context.Context, caller string) error {
+	v, err := serverVersion(ctx, r.conn)
+	if err != nil {
+		return fmt.Errorf("%s: %w", caller, err)
+	}
+	if v < genericPlanVersion {
+		return fmt.Errorf("%s requires PostgreSQL 16 or newer for EXPLAIN (GENERIC_PLAN), the server is PostgreSQL %d", caller, v/10000)
+	}
+	return nil
+}
+
+// mark runs a statement containing a unique marker and waits until it
+// appears in the log, so every statement finished before is in the log too.
+func (r *QueryRecorder) mark(ctx context.Context, name string) error {
+	marker := markerPrefix(r.id) + name
+	if _, err := r.conn.Exec(ctx, "SELECT '"+marker+"'"); err != nil {
+		return fmt.Errorf("write log marker: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	for {
+		if err := r.readLog(ctx); err != nil {
+			return err
+		}
+		if _, ok := r.log.marks[name]; ok {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("log marker %s did not appear in the server log", marker)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// markerPrefix returns the prefix of the log markers of the recorder id.
+func markerPrefix(id string) string {
+	return "testground-capture-" + id + "-"
+}
+
+// readLog parses the server log lines written since the last read.
+func (r *QueryRecorder) readLog(ctx context.Context) error {
+	lines, err := r.c.base.LogLinesSince(ctx, r.since)
+	if err != nil {
+		return fmt.Errorf("read server log: %w", err)
+	}
+	// The lines logged at since itself were read last time already.
+	skip := r.atSince
+	for _, line := range lines {
+		if line.Time.Equal(r.since) {
+			if skip > 0 {
+				skip--
+				continue
+			}
+			r.atSince++
+		} else {
+			r.since, r.atSince = line.Time, 1
+		}
+		r.log.add(line.Text)
+	}
+	return nil
+}
+
+// Queries returns the statements the server has run since CaptureQueries,
+// in the order they finished. Statements run by the recorder itself, such
+// as EXPLAIN, are left out.
+func (r *QueryRecorder) Queries(t *testing.T) []Query {
+	t.Helper()
+	queries, err := r.queries(context.Background())
+	if err != nil {
+		t.Fatalf("postgres: captured queries: %v", err)
+	}
+	return queries
+}
+
+func (r *QueryRecorder) queries(ctx context.Context) ([]Query, error) {
+	r.seq++
+	end := fmt.Sprintf("sync%d", r.seq)
+	if err := r.mark(ctx, end); err != nil {
+		return nil, err
+	}
+	return slices.Clone(r.log.queries[:r.log.marks[end]]), nil
+}
+
+// capturedLog collects the statements of a capture from the server log, fed
+// to it line by line.
+type capturedLog struct {
+	prefix  string // of the recorder's markers
+	started bool
+	queries []Query
+	// marks holds the number of statements logged before each marker of
+	// the recorder, by marker name.
+	marks map[string]int
+	// last is the index of the statement that a continuation line belongs
+	// to, or -1.
+	last int
+}
+
+func newCapturedLog(prefix string) *capturedLog {
+	return &capturedLog{prefix: prefix, marks: make(map[string]int), last: -1}
+}
+
+// add parses a line of the server log. Statements count from the start
+// marker on; those of the recorder are left out.
+func (l *capturedLog) add(line string) {
+	// The server continues multi-line messages on lines starting with a
+	// tab.
+	if strings.HasPrefix(line, "\t") {
+		if l.last >= 0 {
+			l.queries[l.last].SQL += "\n" + line[1:]
+		}
+		return
+	}
+	l.last = -1
+
+	m := logEntry.FindStringSubmatch(line)
+	if m == nil {
+		return
+	}
+	app, ms, kind, sql := m[1], m[2], m[3], m[4]
+	if app == captureApp {
+		if _, name, ok := strings.Cut(sql, l.prefix); ok {
+			name, _, _ = strings.Cut(name, "'")
+			l.marks[name] = len(l.queries)
+			if name == "start" {
+				l.started = true
+			}
+		}
+		return
+	}
+	// Extended protocol statements are logged once per phase; the execute
+	// phase is the one that runs the statement.
+	if !l.started || strings.HasPrefix(kind, "parse") || strings.HasPrefix(kind, "bind") {
+		return
+	}
+	d, _ := strconv.ParseFloat(ms, 64)
+	l.queries = append(l.queries, Query{
+		SQL:         sql,
+		Duration:    time.Duration(d * float64(time.Millisecond)),
+		Application: app,
+	})
+	l.last = len(l.queries) - 1
+}
+
+// AssertQueryCount fails the test unless exactly n captured statements
+// match the regular expression pattern, e.g. to catch N+1 queries:
+//
+//	rec.AssertQueryCount(t, `(?i)^select .* from orders`, 1)
+func (r *QueryRecorder) AssertQueryCount(t *testing.T, pattern string, n int) {
+	t.Helper()
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		t.Fatalf("AssertQueryCount: %v", err)
+	}
+	queries := r.Queries(t)
+
+	var matched []Query
+	for _, q := range queries {
+		if re.MatchString(q.SQL) {
+			matched = append(matched, q)
+		}
+	}
+	if len(matched) != n {
+		shown := matched
+		if len(shown) == 0 {
+			shown = queries
+		}
+		t.Fatalf("AssertQueryCount %q: expected %d matching statement(s), got %d\n%s",
+			pattern, n, len(matched), formatQueries(shown))
+	}
+}
+
+// AssertNoSeqScan fails the test if a captured SELECT, UPDATE or DELETE on
+// table can only be answered with a sequential scan of it, which usually
+// means an index is missing. Each statement is planned again with EXPLAIN
+// (GENERIC_PLAN) and enable_seqscan off, so the planner uses any usable index
+// even on a table with a handful of test rows. Requires PostgreSQL 16 or
+// newer; on older servers the test fails.
+func (r *QueryRecorder) AssertNoSeqScan(t *testing.T, table string) {
+	t.Helper()
+	ctx := context.Background()
+	if err := r.requireGenericPlan(ctx, "AssertNoSeqScan"); err != nil {
+		t.Fatal(err)
+	}
+	mentions := regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(table) + `\b`)
+	scan := regexp.MustCompile(`Seq Scan on (?:\S+\.)?"?` + regexp.QuoteMeta(table) + `"?\b`)
+
+	var failures []string
+	for _, q := range r.Queries(t) {
+		if !explainable(q.SQL) || !mentions.MatchString(q.SQL) {
+			continue
+		}
+		plan, err := r.explain(ctx, q.SQL, true)
+		if err != nil {
+			t.Logf("AssertNoSeqScan: cannot explain %s: %v", q.SQL, err)
+			continue
+		}
+		if scan.MatchString(plan) {
+			failures = append(failures, fmt.Sprintf("%s\n%s", q.SQL, indent(plan)))
+		}
+	}
+	if len(failures) > 0 {
+		t.Fatalf("AssertNoSeqScan %q: %d statement(s) scan the whole table:\n\n%s",
+			table, len(failures), strings.Join(failures, "\n\n"))
+	}
+}
+
+// ExplainSlow logs the plan of every captured statement that took at least
+// threshold, slowest first. Like AssertNoSeqScan it requires PostgreSQL 16 or
+// newer; on older servers the test fails.
+func (r *QueryRecorder) ExplainSlow(t *testing.T, threshold time.Duration) {
+	t.Helper()
+	ctx := context.Background()
+	if err := r.requireGenericPlan(ctx, "ExplainSlow"); err != nil {
+		t.Fatal(err)
+	}
+
+	var slow []Query
+	for _, q := range r.Queries(t) {
+		if q.Duration >= threshold && explainable(q.SQL) {
+			slow = append(slow, q)
+		}
+	}
+	slices.SortStableFunc(slow, func(a, b Query) int {
+		return cmp.Compare(b.Duration, a.Duration)
+	})
+	for _, q := range slow {
+		plan, err := r.explain(ctx, q.SQL, false)
+		if err != nil {
+			t.Logf("slow statement (%s): %s\n  cannot explain: %v", q.Duration, q.SQL, err)
+			continue
+		}
+		t.Logf("slow statement (%s): %s\n%s", q.Duration, q.SQL, indent(plan))
+	}
+}
+
+// explain returns the generic plan of sql. With noSeqScan the planner avoids
+// sequential scans wherever an index can be used.
+func (r *QueryRecorder) explain(ctx context.Context, sql string, noSeqScan bool) (string, error) {
+	conn, err := r.c.appConn(ctx, r.c.cfg.database, captureApp)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close(ctx)
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	// Roll back, so EXPLAIN never has side effects and SET LOCAL ends.
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	if noSeqScan {
+		if _, err := tx.Exec(ctx, "SET LOCAL enable_seqscan = off"); err != nil {
+			return "", err
+		}
+	}
+	// Send the statement as is, so EXPLAIN sees its placeholders instead of
+	// pgx trying to bind them.
+	results, err := tx.Conn().PgConn().Exec(ctx, "EXPLAIN (GENERIC_PLAN) "+sql).ReadAll()
+	if err != nil {
+		return "", err
+	}
+	var lines []string
+	for _, res := range results {
+		for _, row := range res.Rows {
+			lines = append(lines, string(row[0]))
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// explainable reports whether EXPLAIN accepts sql.
+func explainable(sql string) bool {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return false
+	}
+	switch strings.ToLower(fields[0]) {
+	case "select", "update", "delete", "insert", "with", "values":
+		return true
+	}
+	return false
+}
+
+func indent(s string) string {
+	return "    " + strings.ReplaceAll(s, "\n", "\n    ")
+}
+
+func formatQueries(queries []Query) string {
+	if len(queries) == 0 {
+		return "  (no statements)"
+	}
+	var sb strings.Builder
+	for i, q := range queries {
+		fmt.Fprintf(&sb, "  [%d] (%s) %s\n", i, q.Duration, strings.Join(strings.Fields(q.SQL), " "))
+	}
+	return sb.String()
+}
@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCapturedLog(t *testing.T) {
+	// The log arrives in two reads, the second one in the middle of a
+	// multi-line statement.
+	reads := []string{`tgq|psql|LOG:  duration: 0.100 ms  statement: SELECT 'before'
+tgq|testground-capture|LOG:  duration: 0.010 ms  statement: SELECT 'testground-capture-abc-start'
+tgq|app|LOG:  duration: 0.020 ms  parse stmtcache_1: SELECT * FROM users WHERE id = $1
+tgq|app|LOG:  duration: 0.030 ms  bind stmtcache_1: SELECT * FROM users WHERE id = $1
+tgq|app|DETAIL:  parameters: $1 = '1'
+tgq|app|LOG:  duration: 1.500 ms  execute stmtcache_1: SELECT * FROM users WHERE id = $1
+tgq|app|DETAIL:  parameters: $1 = '1'
+tgq|testground-capture|LOG:  duration: 0.010 ms  statement: SELECT 'testground-capture-other-start'
+tgq|testground-capture|LOG:  duration: 0.200 ms  statement: EXPLAIN (GENERIC_PLAN) SELECT 1
+tgq||LOG:  duration: 2.000 ms  statement: UPDATE users
+	SET name = 'x'`, `	WHERE id = 2
+2026-10-17 10:00:00.000 UTC [1] LOG:  checkpoint starting: time
+tgq|testground-capture|LOG:  duration: 0.010 ms  statement: SELECT 'testground-capture-abc-sync1'
+tgq|app|LOG:  duration: 0.100 ms  statement: SELECT 'after'`}
+
+	l := newCapturedLog(markerPrefix("abc"))
+	for _, read := range reads {
+		for _, line := range strings.Split(read, "\n") {
+			l.add(line)
+		}
+	}
+
+	if n, ok := l.marks["sync1"]; !ok || n != 2 {
+		t.Fatalf("marks = %v, want sync1 after 2 statements", l.marks)
+	}
+	got := l.queries[:l.marks["sync1"]]
+	want := []Query{
+		{SQL: "SELECT * FROM users WHERE id = $1", Duration: 1500 * time.Microsecond, Application: "app"},
+		{SQL: "UPDATE users\nSET name = 'x'\nWHERE id = 2", Duration: 2 * time.Millisecond},
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("query %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestExplainable(t *testing.T) {
+	for sql, want := range map[string]bool{
+		"SELECT 1":                       true,
+		"  with x AS (SELECT 1) TABLE x": true,
+		"delete FROM users":              true,
+		"BEGIN":                          false,
+		"SET application_name = 'x'":     false,
+		"":                               false,
+	} {
+		if got := explainable(sql); got != want {
+			t.Errorf("explainable(%q) = %v, want %v", sql, got, want)
+		}
+	}
+}
@@ -0,0 +1,489 @@
+package postgres
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Op is the kind of a row change.
+type Op string
+
+const (
+	Insert   Op = "INSERT"
+	Update   Op = "UPDATE"
+	Delete   Op = "DELETE"
+	Truncate Op = "TRUNCATE"
+)
+
+// cdcApp is the application_name of the connections of a ChangeStream.
+const cdcApp = "testground-cdc"
+
+// cdcSlotPrefix starts the names of the replication slots of ChangeStreams.
+const cdcSlotPrefix = "testground_cdc_"
+
+// cdcPollInterval is how often a ChangeStream reads the replication slot
+// while waiting for changes.
+const cdcPollInterval = 100 * time.Millisecond
+
+// Change is a row change decoded from the logical replication stream.
+type Change struct {
+	Schema string
+	Table  string
+	Op     Op
+	// Old holds the old row of an Update or Delete, as far as the table's
+	// replica identity provides it: by default only the primary key, and for
+	// an Update only if the key changed. REPLICA IDENTITY FULL provides the
+	// whole row.
+	Old map[string]any
+	// New holds the new row of an Insert or Update. Unchanged TOASTed
+	// values of an Update are missing.
+	New map[string]any
+	// CommitTime is when the transaction that made the change committed.
+	CommitTime time.Time
+}
+
+// ChangeStream reads the changes committed to the database from a logical
+// replication slot, returned by CaptureChanges.
+type ChangeStream struct {
+	c           *Container
+	slot        string
+	publication string
+
+	// mu serializes reads of the slot and guards the fields below.
+	mu        sync.Mutex
+	relations map[uint32]relation
+	types     *pgtype.Map
+	commit    time.Time
+	history   []Change
+	// err is the error that ended the channel of Changes.
+	err error
+}
+
+// relation describes a table as announced by pgoutput.
+type relation struct {
+	schema, table string
+	columns       []column
+}
+
+type column struct {
+	name string
+	oid  uint32
+}
+
+// CaptureChanges records the rows inserted, updated and deleted in tables,
+// or in every table if none are given, from now until the end of the test.
+// It creates a publication and a replication slot with the pgoutput plugin,
+// and removes both in t.Cleanup. The tables must exist.
+//
+// The container must be started with WithLogicalReplication; otherwise
+// CaptureChanges fails the test at once.
+func (c *Container) CaptureChanges(t *testing.T, tables ...string) *ChangeStream {
+	t.Helper()
+	ctx := context.Background()
+
+	s, err := c.startCDC(ctx, tables)
+	if err != nil {
+		t.Fatalf("postgres: capture changes: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := s.close(context.Background()); err != nil {
+			t.Logf("warning: postgres: stop capturing changes: %v", err)
+		}
+	})
+	return s
+}
+
+func (c *Container) startCDC(ctx context.Context, tables []string) (*ChangeStream, error) {
+	conn, err := c.appConn(ctx, c.cfg.database, cdcApp)
+	if err != nil {
+		return nil, fmt.Errorf("connect: %w", err)
+	}
+	defer conn.Close(ctx)
+
+	id := strings.ToLower(rand.Text()[:12])
+	s := &ChangeStream{
+		c:           c,
+		slot:        cdcSlotPrefix + id,
+		publication: "testground_cdc_" + id,
+		relations:   make(map[uint32]relation),
+		types:       pgtype.NewMap(),
+	}
+
+	var walLevel string
+	if err := conn.QueryRow(ctx, "SHOW wal_level").Scan(&walLevel); err != nil {
+		return nil, err
+	}
+	if walLevel != "logical" {
+		return nil, fmt.Errorf("wal_level is %q, want logical: start the container with WithLogicalReplication", walLevel)
+	}
+
+	target := "ALL TABLES"
+	if len(tables) > 0 {
+		quoted := make([]string, len(tables))
+		for i, table := range tables {
+			quoted[i] = identifier(table)
+		}
+		target = "TABLE " + strings.Join(quoted, ", ")
+	}
+	if _, err := conn.Exec(ctx, fmt.Sprintf("CREATE PUBLICATION %s FOR %s", s.publication, target)); err != nil {
+		return nil, fmt.Errorf("create publication: %w", err)
+	}
+	// The slot is permanent, so the stream needs no session between reads
+	// that would keep Snapshot and NewIsolatedDB from copying the database.
+	// Reset drops slots left behind by a crashed run on a reused container.
+	if _, err := conn.Exec(ctx, "SELECT pg_create_logical_replication_slot($1, 'pgoutput')", s.slot); err != nil {
+		conn.Exec(ctx, "DROP PUBLICATION IF EXISTS "+s.publication) //nolint:errcheck
+		return nil, fmt.Errorf("create replication slot: %w", err)
+	}
+	return s, nil
+}
+
+func (s *ChangeStream) close(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	conn, err := s.c.appConn(ctx, s.c.cfg.database, cdcApp)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+	if _, err := conn.Exec(ctx, "SELECT pg_drop_replication_slot($1)", s.slot); err != nil {
+		return err
+	}
+	_, err = conn.Exec(ctx, "DROP PUBLICATION IF EXISTS "+s.publication)
+	return err
+}
+
+// Poll returns the changes committed since the previous Poll, or since
+// CaptureChanges. It does not wait for new changes. If a message cannot be
+// decoded, the changes decoded before it are returned with the error and
+// kept in History.
+func (s *ChangeStream) Poll(ctx context.Context) ([]Change, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conn, err := s.c.appConn(ctx, s.c.cfg.database, cdcApp)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close(ctx)
+	rows, err := conn.Query(ctx,
+		"SELECT data FROM pg_logical_slot_get_binary_changes($1, NULL, NULL, 'proto_version', '1', 'publication_names', $2)",
+		s.slot, s.publication)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := pgx.CollectRows(rows, pgx.RowTo[[]byte])
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	for _, msg := range messages {
+		decoded, err := s.decode(msg)
+		if err != nil {
+			// The slot has already moved past these messages, so the
+			// changes decoded so far are kept rather than lost.
+			s.history = append(s.history, changes...)
+			if len(msg) > 0 {
+				return changes, fmt.Errorf("decode pgoutput message %q: %w", msg[:1], err)
+			}
+			return changes, fmt.Errorf("decode pgoutput message: %w", err)
+		}
+		changes = append(changes, decoded...)
+	}
+	s.history = append(s.history, changes...)
+	return changes, nil
+}
+
+// Changes streams the changes committed from now on until ctx is done, then
+// closes the channel. Changes that arrived before the call are not sent. If
+// reading the slot fails, the channel is closed early and Err returns the
+// error.
+func (s *ChangeStream) Changes(ctx context.Context) <-chan Change {
+	ch := make(chan Change)
+	go func() {
+		defer close(ch)
+		for {
+			changes, err := s.Poll(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					s.mu.Lock()
+					s.err = err
+					s.mu.Unlock()
+				}
+				return
+			}
+			for _, change := range changes {
+				select {
+				case ch <- change:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(cdcPollInterval):
+			}
+		}
+	}()
+	return ch
+}
+
+// Err returns the error that closed the channel of Changes before its
+// context was done, or nil.
+func (s *ChangeStream) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// History returns every change received since CaptureChanges.
+func (s *ChangeStream) History() []Change {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.history)
+}
+
+// AssertChanged fails the test unless a change of kind op to table, received
+// since CaptureChanges, has the column values in match: the new row of an
+// Insert or Update, the old row of a Delete. A nil match accepts any change.
+// It waits up to 10 seconds for the change to arrive.
+//
+//	cdc.AssertChanged(t, "orders", postgres.Update, map[string]any{"id": 42, "status": "paid"})
+func (s *ChangeStream) AssertChanged(t *testing.T, table string, op Op, match map[string]any) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for {
+		if _, err := s.Poll(ctx); err != nil && ctx.Err() == nil {
+			t.Fatalf("AssertChanged: %v", err)
+		}
+		history := s.History()
+		for _, change := range history {
+			if change.matches(table, op, match) {
+				return
+			}
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("AssertChanged: no %s on %s with %s\n%s",
+				op, table, formatRow(slices.Sorted(maps.Keys(match)), match), formatChanges(history))
+		case <-time.After(cdcPollInterval):
+		}
+	}
+}
+
+func (c Change) matches(table string, op Op, match map[string]any) bool {
+	if c.Op != op || (table != c.Table && table != c.Schema+"."+c.Table) {
+		return false
+	}
+	row := c.New
+	if op == Delete {
+		row = c.Old
+	}
+	for col, want := range match {
+		got, ok := row[col]
+		if !ok || !valuesEqual(want, got) {
+			return false
+		}
+	}
+	return true
+}
+
+func formatChanges(changes []Change) string {
+	if len(changes) == 0 {
+		return "  (no changes)"
+	}
+	var sb strings.Builder
+	for i, c := range changes {
+		fmt.Fprintf(&sb, "  [%d] %s %s.%s", i, c.Op, c.Schema, c.Table)
+		if c.Old != nil {
+			fmt.Fprintf(&sb, " old=%s", formatRow(slices.Sorted(maps.Keys(c.Old)), c.Old))
+		}
+		if c.New != nil {
+			fmt.Fprintf(&sb, " new=%s", formatRow(slices.Sorted(maps.Keys(c.New)), c.New))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// pgEpoch is the origin of PostgreSQL timestamps.
+var pgEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// decode decodes one pgoutput message, see "Logical Replication Message
+// Formats" in the PostgreSQL documentation. Only row changes produce
+// Changes; Begin and Relation messages update the stream's state.
+func (s *ChangeStream) decode(msg []byte) ([]Change, error) {
+	r := &reader{buf: msg}
+	switch kind := r.byte(); kind {
+	case 'B':
+		r.uint64() // final LSN
+		s.commit = pgEpoch.Add(time.Duration(int64(r.uint64())) * time.Microsecond)
+		return nil, r.err
+	case 'R':
+		id := r.uint32()
+		rel := relation{schema: r.string(), table: r.string()}
+		r.byte() // replica identity
+		for range r.uint16() {
+			r.byte() // flags
+			rel.columns = append(rel.columns, column{name: r.string(), oid: r.uint32()})
+			r.uint32() // type modifier
+		}
+		if r.err == nil {
+			s.relations[id] = rel
+		}
+		return nil, r.err
+	case 'I', 'U', 'D':
+		rel, ok := s.relations[r.uint32()]
+		if !ok {
+			return nil, errors.New("change for an unknown relation")
+		}
+		change := Change{Schema: rel.schema, Table: rel.table, CommitTime: s.commit}
+		change.Op = map[byte]Op{'I': Insert, 'U': Update, 'D': Delete}[kind]
+		for r.err == nil && len(r.buf) > 0 {
+			switch part := r.byte(); part {
+			case 'K', 'O':
+				change.Old = r.tuple(rel, s.types)
+			case 'N':
+				change.New = r.tuple(rel, s.types)
+			default:
+				return nil, fmt.Errorf("unexpected tuple type %q", part)
+			}
+		}
+		return []Change{change}, r.err
+	case 'T':
+		n := r.uint32()
+		r.byte() // options
+		var changes []Change
+		for range n {
+			rel, ok := s.relations[r.uint32()]
+			if !ok {
+				return nil, errors.New("truncate of an unknown relation")
+			}
+			changes = append(changes, Change{Schema: rel.schema, Table: rel.table, Op: Truncate, CommitTime: s.commit})
+		}
+		return changes, r.err
+	default:
+		// Commit, Origin, Type and Message carry nothing to report.
+		return nil, nil
+	}
+}
+
+// reader decodes the big-endian fields of a pgoutput message. The first
+// error sticks and makes every later read return zero values.
+type reader struct {
+	buf []byte
+	err error
+}
+
+func (r *reader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.buf) < n {
+		r.err = errors.New("message too short")
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *reader) byte() byte {
+	if b := r.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *reader) uint16() uint16 {
+	if b := r.next(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *reader) uint32() uint32 {
+	if b := r.next(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *reader) uint64() uint64 {
+	if b := r.next(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (r *reader) string() string {
+	if r.err != nil {
+		return ""
+	}
+	i := slices.Index(r.buf, 0)
+	if i < 0 {
+		r.err = errors.New("unterminated string")
+		return ""
+	}
+	s := string(r.buf[:i])
+	r.buf = r.buf[i+1:]
+	return s
+}
+
+// tuple decodes TupleData into column values, converting the text of each
+// value to its Go type with types.
+func (r *reader) tuple(rel relation, types *pgtype.Map) map[string]any {
+	n := int(r.uint16())
+	row := make(map[string]any, n)
+	for i := range n {
+		kind := r.byte()
+		if r.err != nil {
+			return nil
+		}
+		if i >= len(rel.columns) {
+			r.err = errors.New("more values than columns")
+			return nil
+		}
+		col := rel.columns[i]
+		switch kind {
+		case 'n':
+			row[col.name] = nil
+		case 'u':
+			// Unchanged TOASTed value: not sent, so not reported.
+		case 't':
+			data := r.next(int(r.uint32()))
+			row[col.name] = decodeText(types, col.oid, data)
+		default:
+			r.err = fmt.Errorf("unexpected value kind %q", kind)
+			return nil
+		}
+	}
+	return row
+}
+
+// decodeText converts a value in text format to the Go type pgx would scan it
+// into, or keeps it as a string for unknown types.
+func decodeText(types *pgtype.Map, oid uint32, data []byte) any {
+	if typ, ok := types.TypeForOID(oid); ok {
+		if v, err := typ.Codec.DecodeValue(types, oid, pgtype.TextFormatCode, data); err == nil {
+			return v
+		}
+	}
+	return string(data)
+}
@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// message builds a pgoutput message.
+type message struct{ bytes.Buffer }
+
+func (m *message) u8(v byte) *message    { m.WriteByte(v); return m }
+func (m *message) u16(v uint16) *message { binary.Write(m, binary.BigEndian, v); return m }
+func (m *message) u32(v uint32) *message { binary.Write(m, binary.BigEndian, v); return m }
+func (m *message) u64(v uint64) *message { binary.Write(m, binary.BigEndian, v); return m }
+func (m *message) str(s string) *message { m.WriteString(s); m.WriteByte(0); return m }
+func (m *message) text(s string) *message {
+	m.u8('t').u32(uint32(len(s)))
+	m.WriteString(s)
+	return m
+}
+
+func TestChangeStream_Decode(t *testing.T) {
+	s := &ChangeStream{relations: make(map[uint32]relation), types: pgtype.NewMap()}
+	decode := func(m *message) []Change {
+		t.Helper()
+		changes, err := s.decode(m.Bytes())
+		if err != nil {
+			t.Fatalf("decode(%q) error = %v", m.Bytes()[:1], err)
+		}
+		return changes
+	}
+
+	commit := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	decode(new(message).u8('B').u64(1).u64(uint64(commit.Sub(pgEpoch).Microseconds())).u32(7))
+	decode(new(message).u8('R').u32(16384).str("public").str("users").u8('d').u16(2).
+		u8(1).str("id").u32(pgtype.Int8OID).u32(0xFFFFFFFF).
+		u8(0).str("name").u32(pgtype.TextOID).u32(0xFFFFFFFF))
+
+	insert := decode(new(message).u8('I').u32(16384).u8('N').u16(2).text("1").text("Alice"))
+	update := decode(new(message).u8('U').u32(16384).
+		u8('O').u16(2).text("1").text("Alice").
+		u8('N').u16(2).text("1").u8('n'))
+	del := decode(new(message).u8('D').u32(16384).u8('K').u16(2).text("1").u8('n'))
+	truncate := decode(new(message).u8('T').u32(1).u8(0).u32(16384))
+
+	if len(insert) != 1 || insert[0].Op != Insert || insert[0].Table != "users" || !insert[0].CommitTime.Equal(commit) {
+		t.Fatalf("insert = %+v", insert)
+	}
+	if got := insert[0].New; got["id"] != int64(1) || got["name"] != "Alice" {
+		t.Errorf("insert New = %v, want {id: 1, name: Alice}", got)
+	}
+	if got := update[0]; got.Op != Update || got.Old["name"] != "Alice" || got.New["name"] != nil {
+		t.Errorf("update = %+v", got)
+	}
+	if got := del[0]; got.Op != Delete || got.Old["id"] != int64(1) || got.New != nil {
+		t.Errorf("delete = %+v", got)
+	}
+	if len(truncate) != 1 || truncate[0].Op != Truncate || truncate[0].Table != "users" {
+		t.Errorf("truncate = %+v", truncate)
+	}
+
+	if !update[0].matches("public.users", Update, map[string]any{"id": 1, "name": nil}) {
+		t.Error("matches() = false for the update")
+	}
+	if insert[0].matches("users", Insert, map[string]any{"name": "Bob"}) {
+		t.Error("matches() = true for a different value")
+	}
+}
+
+func TestChangeStream_DecodeUnknownRelation(t *testing.T) {
+	s := &ChangeStream{relations: make(map[uint32]relation), types: pgtype.NewMap()}
+	if _, err := s.decode(new(message).u8('I').u32(1).u8('N').u16(0).Bytes()); err == nil {
+		t.Error("decode() of a change for an unknown relation returned no error")
+	}
+}
@@ -0,0 +1,423 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	dockercontainer "github.com/docker/docker/api/types/container"
+	"github.com/jackc/pgx/v5"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/dsvdev/testground"
+)
+
+// primaryAlias is the name of the initial primary on the internal network.
+const primaryAlias = "primary"
+
+// replicaDataDir is the data directory of a replica. It is created by
+// pg_basebackup, so it must not be a mount point owned by root.
+const (
+	replicaDataDir      = "/var/lib/postgresql/replica"
+	replicaTmpfsDir     = "/var/lib/postgresql/tmpfs"
+	replicaTmpfsDataDir = replicaTmpfsDir + "/replica"
+)
+
+// replicationHBA lets the replicas connect for replication with the
+// configured password. It runs after the image has written pg_hba.conf.
+const replicationHBA = `echo "host replication all all md5" >> "$PGDATA/pg_hba.conf"
+`
+
+// replicaScript clones the primary with pg_basebackup on first start, retrying
+// until the primary accepts replication connections, and runs the server as
+// a hot standby. The arguments are passed on to postgres.
+const replicaScript = `set -e
+if [ ! -s "$PGDATA/PG_VERSION" ]; then
+	until pg_basebackup -h "$PRIMARY_HOST" -U "$POSTGRES_USER" -D "$PGDATA" -X stream; do
+		rm -rf "$PGDATA"
+		sleep 1
+	done
+	touch "$PGDATA/standby.signal"
+	echo "primary_conninfo = '$PRIMARY_CONNINFO'" >> "$PGDATA/postgresql.auto.conf"
+fi
+exec postgres "$@"
+`
+
+// Cluster manages a primary and streaming replicas connected via a private
+// internal network, for read-after-write, replication lag and failover tests.
+// Every node is a *Container, so the single-server API works on each of them;
+// replicas are read-only.
+type Cluster struct {
+	// mu guards the fields below, which Promote changes.
+	mu       sync.RWMutex
+	primary  *Container
+	replicas []*Container
+	// aliases maps every node to its name on innerNet.
+	aliases map[*Container]string
+	// retired holds former primaries, which keep running after Promote.
+	retired []*Container
+
+	innerNet *testground.Network
+	cfg      config
+}
+
+// NewCluster creates an internal Docker network, starts the primary, then
+// starts the replicas set with WithReplicas (default 1), which stream the WAL
+// from it. On any error the already-started resources are stopped in reverse
+// order.
+//
+// The options apply to every node, except that WithPort, WithInitScripts and
+// WithExtensions only apply to the primary; the replicas get the rest from
+// replication. With WithNetworkAlias the primary gets the alias and replica i
+// alias-replica-i. WithReuse is not supported.
+func NewCluster(ctx context.Context, opts ...Option) (*Cluster, error) {
+	cfg := defaultConfig()
+	cfg.replicas = 1
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.reuseName != "" {
+		return nil, errors.New("postgres: WithReuse is not supported by NewCluster")
+	}
+	if cfg.replicas < 1 {
+		return nil, fmt.Errorf("postgres: cluster needs at least one replica, got %d", cfg.replicas)
+	}
+
+	// Step 1: internal network for replication.
+	innerNet, err := testground.NewNetwork(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("postgres: create internal network: %w", err)
+	}
+	cl := &Cluster{
+		aliases:  make(map[*Container]string),
+		innerNet: innerNet,
+		cfg:      cfg,
+	}
+
+	// Step 2: the primary.
+	primary, err := start(ctx, cfg, func(req *testcontainers.ContainerRequest) {
+		req.Files = append(req.Files, testcontainers.ContainerFile{
+			Reader:            strings.NewReader(replicationHBA),
+			ContainerFilePath: "/docker-entrypoint-initdb.d/zz_testground_replication.sh",
+			FileMode:          0o755,
+		})
+		cl.joinNetworks(req, cfg, primaryAlias)
+	})
+	if err != nil {
+		innerNet.Terminate(ctx) //nolint:errcheck
+		return nil, fmt.Errorf("postgres: start primary: %w", err)
+	}
+	cl.primary = primary
+	cl.aliases[primary] = primaryAlias
+
+	// Step 3: the replicas, cloned from the primary.
+	for i := range cfg.replicas {
+		replica, err := cl.startReplica(ctx, i)
+		if err != nil {
+			cl.Terminate(ctx) //nolint:errcheck
+			return nil, fmt.Errorf("postgres: start replica %d: %w", i, err)
+		}
+		cl.replicas = append(cl.replicas, replica)
+		cl.aliases[replica] = replicaAlias(i)
+	}
+	return cl, nil
+}
+
+// startReplica starts replica i, streaming from the primary.
+func (cl *Cluster) startReplica(ctx context.Context, i int) (*Container, error) {
+	cfg := cl.cfg
+	cfg.port = ""
+	cfg.initScripts = ""
+	cfg.extensions = nil
+	if cfg.networkAlias != "" {
+		cfg.networkAlias = cfg.networkAlias + "-" + replicaAlias(i)
+	}
+
+	dataDir := replicaDataDir
+	if cfg.tmpfs {
+		dataDir = replicaTmpfsDataDir
+	}
+	return start(ctx, cfg, func(req *testcontainers.ContainerRequest) {
+		req.Entrypoint = []string{"bash", "-c", replicaScript, "replica"}
+		req.Cmd = cfg.serverArgs()
+		req.Env["PGDATA"] = dataDir
+		req.Env["PGPASSWORD"] = cfg.password
+		req.Env["PRIMARY_HOST"] = primaryAlias
+		req.Env["PRIMARY_CONNINFO"] = cl.primaryConninfo(primaryAlias, replicaAlias(i))
+		if cfg.tmpfs {
+			req.Tmpfs = map[string]string{replicaTmpfsDir: "rw"}
+		}
+		// pg_basebackup must create the data directory as the server user,
+		// which the entrypoint of the image would otherwise switch to.
+		req.ConfigModifier = func(c *dockercontainer.Config) {
+			c.User = "postgres"
+		}
+		req.WaitingFor = wait.ForAll(
+			wait.ForListeningPort("5432/tcp"),
+			wait.ForLog("database system is ready to accept read-only connections"),
+		)
+		cl.joinNetworks(req, cfg, replicaAlias(i))
+	})
+}
+
+// joinNetworks attaches a node to the internal network under alias, and to
+// the network of WithNetwork if set.
+func (cl *Cluster) joinNetworks(req *testcontainers.ContainerRequest, cfg config, alias string) {
+	req.Networks = []string{cl.innerNet.Name()}
+	req.NetworkAliases = map[string][]string{
+		cl.innerNet.Name(): {alias},
+	}
+	if cfg.networkName != "" {
+		req.Networks = append(req.Networks, cfg.networkName)
+		if cfg.networkAlias != "" {
+			req.NetworkAliases[cfg.networkName] = []string{cfg.networkAlias}
+		}
+	}
+}
+
+// primaryConninfo returns the primary_conninfo of a replica named
+// application that streams from host.
+func (cl *Cluster) primaryConninfo(host, application string) string {
+	return fmt.Sprintf("host=%s port=5432 user=%s password=%s application_name=%s",
+		host, cl.cfg.user, cl.cfg.password, application)
+}
+
+func replicaAlias(i int) string {
+	return fmt.Sprintf("replica-%d", i)
+}
+
+// Primary returns the current primary, which changes with Promote.
+func (cl *Cluster) Primary() *Container {
+	cl.mu.RLock()
+	defer cl.mu.RUnlock()
+	return cl.primary
+}
+
+// Replica returns replica i, in the order of ReplicaConnectionStrings.
+func (cl *Cluster) Replica(i int) *Container {
+	cl.mu.RLock()
+	defer cl.mu.RUnlock()
+	return cl.replicas[i]
+}
+
+// Replicas returns the current replicas. Promote removes the promoted one.
+func (cl *Cluster) Replicas() []*Container {
+	cl.mu.RLock()
+	defer cl.mu.RUnlock()
+	return append([]*Container(nil), cl.replicas...)
+}
+
+// PrimaryConnectionString returns the connection string of the primary, for
+// writes.
+func (cl *Cluster) PrimaryConnectionString() string {
+	return cl.Primary().ConnectionString()
+}
+
+// ReplicaConnectionStrings returns the connection strings of the replicas,
+// for reads.
+func (cl *Cluster) ReplicaConnectionStrings() []string {
+	var conns []string
+	for _, r := range cl.Replicas() {
+		conns = append(conns, r.ConnectionString())
+	}
+	return conns
+}
+
+// NetworkPrimaryConnectionString returns the connection string of the primary
+// for containers inside the network set with WithNetwork.
+func (cl *Cluster) NetworkPrimaryConnectionString() string {
+	return cl.Primary().NetworkConnectionString()
+}
+
+// NetworkReplicaConnectionStrings returns the connection strings of the
+// replicas for containers inside the network set with WithNetwork.
+func (cl *Cluster) NetworkReplicaConnectionStrings() []string {
+	var conns []string
+	for _, r := range cl.Replicas() {
+		conns = append(conns, r.NetworkConnectionString())
+	}
+	return conns
+}
+
+// SetReplayDelay makes every replica apply a transaction only d after it was
+// committed on the primary, to test code that reads its own writes from a
+// replica. Zero turns the delay off. The WAL is still received immediately,
+// so removing the delay catches up at once. Before PostgreSQL 13, which reads
+// the setting only at startup, every replica is restarted.
+func (cl *Cluster) SetReplayDelay(ctx context.Context, d time.Duration) error {
+	for i, r := range cl.Replicas() {
+		if err := setRecoveryConfig(ctx, r, "recovery_min_apply_delay", fmt.Sprintf("%dms", d.Milliseconds())); err != nil {
+			return fmt.Errorf("postgres: set replay delay on replica %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// WaitReplicated waits until every replica has replayed all WAL the primary
+// had written when it was called. With a replay delay it waits at least as
+// long as the delay.
+func (cl *Cluster) WaitReplicated(ctx context.Context) error {
+	var lsn string
+	primary := cl.Primary()
+	if err := queryRow(ctx, primary, "SELECT pg_current_wal_lsn()::text", &lsn); err != nil {
+		return fmt.Errorf("postgres: read primary WAL position: %w", err)
+	}
+	for i, r := range cl.Replicas() {
+		for {
+			var caughtUp bool
+			err := queryRow(ctx, r, "SELECT coalesce(pg_last_wal_replay_lsn() >= $1::pg_lsn, false)", &caughtUp, lsn)
+			if err != nil {
+				return fmt.Errorf("postgres: read replica %d WAL position: %w", i, err)
+			}
+			if caughtUp {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("postgres: replica %d did not replay %s: %w", i, lsn, ctx.Err())
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+	}
+	return nil
+}
+
+// Promote turns replica i into the primary, as a failover would, and points
+// the other replicas at it. The former primary is not stopped: stop or pause
+// it first to simulate its failure, or keep it running to test split brain.
+// Afterwards Primary and PrimaryConnectionString return the new primary and
+// the replica indexes above i shift down by one.
+//
+// Replicas that had received WAL the new primary had not, e.g. because of a
+// replay delay, may fail to follow it. Before PostgreSQL 13, which reads
+// primary_conninfo only at startup, the other replicas are restarted.
+func (cl *Cluster) Promote(ctx context.Context, i int) error {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if i < 0 || i >= len(cl.replicas) {
+		return fmt.Errorf("postgres: promote: no replica %d", i)
+	}
+	promoted := cl.replicas[i]
+
+	var ok bool
+	if err := queryRow(ctx, promoted, "SELECT pg_promote(true, 60)", &ok); err != nil {
+		return fmt.Errorf("postgres: promote replica %d: %w", i, err)
+	}
+	if !ok {
+		return fmt.Errorf("postgres: promote replica %d: not promoted within 60s", i)
+	}
+
+	replicas := append(append([]*Container(nil), cl.replicas[:i]...), cl.replicas[i+1:]...)
+	for _, r := range replicas {
+		conninfo := cl.primaryConninfo(cl.aliases[promoted], cl.aliases[r])
+		if err := setRecoveryConfig(ctx, r, "primary_conninfo", conninfo); err != nil {
+			return fmt.Errorf("postgres: point %s at new primary: %w", cl.aliases[r], err)
+		}
+	}
+
+	cl.retired = append(cl.retired, cl.primary)
+	cl.primary = promoted
+	cl.replicas = replicas
+	return nil
+}
+
+// Healthy reports whether every node accepts connections.
+func (cl *Cluster) Healthy(ctx context.Context) error {
+	if err := cl.Primary().Healthy(ctx); err != nil {
+		return fmt.Errorf("primary: %w", err)
+	}
+	for i, r := range cl.Replicas() {
+		if err := r.Healthy(ctx); err != nil {
+			return fmt.Errorf("replica %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Logs returns the server log of the primary produced so far. Use Replica(i)
+// for the log of a replica. The caller must close the returned reader.
+func (cl *Cluster) Logs(ctx context.Context) (io.ReadCloser, error) {
+	return cl.Primary().Logs(ctx)
+}
+
+// LogsSince returns the server log of the primary produced since the Docker
+// engine time since. The caller must close the returned reader.
+func (cl *Cluster) LogsSince(ctx context.Context, since time.Time) (io.ReadCloser, error) {
+	return cl.Primary().LogsSince(ctx, since)
+}
+
+// Terminate stops the replicas, then the primary and former primaries, then
+// the internal network.
+func (cl *Cluster) Terminate(ctx context.Context) error {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	var errs []error
+	nodes := append(append([]*Container(nil), cl.replicas...), cl.primary)
+	for _, n := range append(nodes, cl.retired...) {
+		if n == nil {
+			continue
+		}
+		if err := n.Terminate(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := cl.innerNet.Terminate(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// reloadRecoveryVersion is the first server version that applies
+// primary_conninfo and recovery_min_apply_delay on reload rather than only at
+// startup.
+const reloadRecoveryVersion = 130000
+
+// setRecoveryConfig changes a recovery setting of a replica with ALTER
+// SYSTEM. Servers before PostgreSQL 13 are restarted to apply it, the others
+// reload their configuration.
+func setRecoveryConfig(ctx context.Context, c *Container, key, value string) error {
+	conn, err := c.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+	// ALTER SYSTEM does not take parameters.
+	stmt := fmt.Sprintf("ALTER SYSTEM SET %s = %s", pgx.Identifier{key}.Sanitize(), quoteLiteral(value))
+	if _, err := conn.Exec(ctx, stmt); err != nil {
+		return err
+	}
+	v, err := serverVersion(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if v < reloadRecoveryVersion {
+		conn.Close(ctx)
+		if err := c.Restart(ctx); err != nil {
+			return fmt.Errorf("restart to apply %s: %w", key, err)
+		}
+		return nil
+	}
+	_, err = conn.Exec(ctx, "SELECT pg_reload_conf()")
+	return err
+}
+
+// queryRow runs a single-row query on a node and scans the result into dst.
+func queryRow(ctx context.Context, c *Container, sql string, dst any, args ...any) error {
+	conn, err := c.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+	return conn.QueryRow(ctx, sql, args...).Scan(dst)
+}
+
+// quoteLiteral quotes s as an SQL string literal.
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
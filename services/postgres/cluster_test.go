@@ -0,0 +1,183 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/dsvdev/testground/services/postgres"
+)
+
+func TestNewCluster_InvalidOptions(t *testing.T) {
+	ctx := context.Background()
+	if _, err := postgres.NewCluster(ctx, postgres.WithReplicas(0)); err == nil {
+		t.Error("NewCluster(WithReplicas(0)) error = nil, want error")
+	}
+	if _, err := postgres.NewCluster(ctx, postgres.WithReuse("cluster")); err == nil {
+		t.Error("NewCluster(WithReuse) error = nil, want error")
+	}
+}
+
+func TestCluster(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	defer cancel()
+
+	cl, err := postgres.NewCluster(ctx, postgres.WithReplicas(2))
+	if err != nil {
+		t.Fatalf("NewCluster() error = %v", err)
+	}
+	t.Cleanup(func() {
+		if err := cl.Terminate(context.Background()); err != nil {
+			t.Errorf("Terminate() error = %v", err)
+		}
+	})
+	if got := len(cl.ReplicaConnectionStrings()); got != 2 {
+		t.Fatalf("len(ReplicaConnectionStrings()) = %d, want 2", got)
+	}
+
+	exec := func(connStr, sql string) error {
+		conn, err := pgx.Connect(ctx, connStr)
+		if err != nil {
+			return err
+		}
+		defer conn.Close(ctx)
+		_, err = conn.Exec(ctx, sql)
+		return err
+	}
+	count := func(connStr string) int {
+		t.Helper()
+		conn, err := pgx.Connect(ctx, connStr)
+		if err != nil {
+			t.Fatalf("connect: %v", err)
+		}
+		defer conn.Close(ctx)
+		var n int
+		if err := conn.QueryRow(ctx, "SELECT count(*) FROM items").Scan(&n); err != nil {
+			t.Fatalf("count items: %v", err)
+		}
+		return n
+	}
+
+	if err := exec(cl.PrimaryConnectionString(), "CREATE TABLE items (id int); INSERT INTO items VALUES (1)"); err != nil {
+		t.Fatalf("write to primary: %v", err)
+	}
+	if err := cl.WaitReplicated(ctx); err != nil {
+		t.Fatalf("WaitReplicated() error = %v", err)
+	}
+	for i, connStr := range cl.ReplicaConnectionStrings() {
+		if n := count(connStr); n != 1 {
+			t.Errorf("replica %d has %d items, want 1", i, n)
+		}
+	}
+
+	t.Run("replicas are read-only", func(t *testing.T) {
+		err := exec(cl.ReplicaConnectionStrings()[0], "INSERT INTO items VALUES (2)")
+		var pgErr *pgconn.PgError
+		if !errors.As(err, &pgErr) || pgErr.Code != "25006" {
+			t.Errorf("write to replica error = %v, want read_only_sql_transaction", err)
+		}
+	})
+
+	t.Run("replay delay", func(t *testing.T) {
+		if err := cl.SetReplayDelay(ctx, time.Hour); err != nil {
+			t.Fatalf("SetReplayDelay() error = %v", err)
+		}
+		if err := exec(cl.PrimaryConnectionString(), "INSERT INTO items VALUES (2)"); err != nil {
+			t.Fatalf("write to primary: %v", err)
+		}
+		time.Sleep(time.Second)
+		if n := count(cl.ReplicaConnectionStrings()[0]); n != 1 {
+			t.Errorf("delayed replica has %d items, want 1", n)
+		}
+
+		if err := cl.SetReplayDelay(ctx, 0); err != nil {
+			t.Fatalf("SetReplayDelay(0) error = %v", err)
+		}
+		if err := cl.WaitReplicated(ctx); err != nil {
+			t.Fatalf("WaitReplicated() error = %v", err)
+		}
+		if n := count(cl.ReplicaConnectionStrings()[0]); n != 2 {
+			t.Errorf("replica has %d items after removing the delay, want 2", n)
+		}
+	})
+
+	t.Run("promote", func(t *testing.T) {
+		old := cl.Primary()
+		if err := old.Stop(ctx); err != nil {
+			t.Fatalf("Stop() primary error = %v", err)
+		}
+		promoted := cl.Replica(0)
+		if err := cl.Promote(ctx, 0); err != nil {
+			t.Fatalf("Promote() error = %v", err)
+		}
+		if cl.Primary() != promoted {
+			t.Fatal("Primary() is not the promoted replica")
+		}
+		if got := len(cl.Replicas()); got != 1 {
+			t.Fatalf("len(Replicas()) = %d, want 1", got)
+		}
+
+		if err := exec(cl.PrimaryConnectionString(), "INSERT INTO items VALUES (3)"); err != nil {
+			t.Fatalf("write to new primary: %v", err)
+		}
+		if err := cl.WaitReplicated(ctx); err != nil {
+			t.Fatalf("WaitReplicated() after promote error = %v", err)
+		}
+		if n := count(cl.ReplicaConnectionStrings()[0]); n != 3 {
+			t.Errorf("remaining replica has %d items, want 3", n)
+		}
+	})
+}
+
+// TestCluster_PostgreSQL12 covers SetReplayDelay and Promote on a server that
+// applies recovery settings only at startup.
+func TestCluster_PostgreSQL12(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	defer cancel()
+
+	cl, err := postgres.NewCluster(ctx, postgres.WithVersion("12"), postgres.WithReplicas(2))
+	if err != nil {
+		t.Fatalf("NewCluster() error = %v", err)
+	}
+	t.Cleanup(func() { cl.Terminate(context.Background()) })
+
+	if err := cl.SetReplayDelay(ctx, time.Hour); err != nil {
+		t.Fatalf("SetReplayDelay() error = %v", err)
+	}
+	var delay string
+	conn, err := pgx.Connect(ctx, cl.ReplicaConnectionStrings()[0])
+	if err != nil {
+		t.Fatalf("connect to replica: %v", err)
+	}
+	err = conn.QueryRow(ctx, "SHOW recovery_min_apply_delay").Scan(&delay)
+	conn.Close(ctx)
+	if err != nil || delay != "1h" {
+		t.Fatalf("recovery_min_apply_delay = %q, %v, want 1h", delay, err)
+	}
+	if err := cl.SetReplayDelay(ctx, 0); err != nil {
+		t.Fatalf("SetReplayDelay(0) error = %v", err)
+	}
+
+	if err := cl.Primary().Stop(ctx); err != nil {
+		t.Fatalf("Stop() primary error = %v", err)
+	}
+	if err := cl.Promote(ctx, 0); err != nil {
+		t.Fatalf("Promote() error = %v", err)
+	}
+	conn, err = pgx.Connect(ctx, cl.PrimaryConnectionString())
+	if err != nil {
+		t.Fatalf("connect to new primary: %v", err)
+	}
+	_, err = conn.Exec(ctx, "CREATE TABLE items (id int)")
+	conn.Close(ctx)
+	if err != nil {
+		t.Fatalf("write to new primary: %v", err)
+	}
+	if err := cl.WaitReplicated(ctx); err != nil {
+		t.Fatalf("WaitReplicated() after promote error = %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package postgres
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// IsolatedDB is a database of its own for a single test, created by
+// NewIsolatedDB.
+type IsolatedDB struct {
+	c    *Container
+	name string
+	pool *pgxpool.Pool
+}
+
+// NewIsolatedDB creates a fresh database for the test, a copy of the
+// container's database, and drops it in t.Cleanup. Tests that each use their
+// own IsolatedDB can call t.Parallel against one container.
+//
+// The copy is made with CREATE DATABASE ... TEMPLATE from a template taken
+// from the container's database on the first call, so migrate that database
+// before, e.g. with Migrate in TestMain. Taking the template terminates the
+// other connections to the container's database, as Snapshot does: the pool
+// returned by Pool stays usable but discards its connections, and a service
+// under test must reconnect. Reset discards the template.
+func (c *Container) NewIsolatedDB(t *testing.T) *IsolatedDB {
+	t.Helper()
+	ctx := context.Background()
+
+	db, err := c.newIsolatedDB(ctx)
+	if err != nil {
+		t.Fatalf("postgres: create isolated database: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := db.drop(context.Background()); err != nil {
+			t.Logf("warning: postgres: drop isolated database %s: %v", db.name, err)
+		}
+	})
+	return db
+}
+
+func (c *Container) newIsolatedDB(ctx context.Context) (*IsolatedDB, error) {
+	c.templateMu.Lock()
+	defer c.templateMu.Unlock()
+
+	conn, err := c.maintenanceConn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("connect: %w", err)
+	}
+	defer conn.Close(ctx)
+
+	if !c.template {
+		if err := c.createTemplate(ctx, conn); err != nil {
+			return nil, err
+		}
+		c.template = true
+	}
+
+	name := fmt.Sprintf("%s_%s", c.cfg.database, strings.ToLower(rand.Text()[:12]))
+	if _, err := conn.Exec(ctx, fmt.Sprintf("CREATE DATABASE %s TEMPLATE %s",
+		pgx.Identifier{name}.Sanitize(), pgx.Identifier{c.templateName()}.Sanitize())); err != nil {
+		return nil, err
+	}
+
+	db := &IsolatedDB{c: c, name: name}
+	pool, err := pgxpool.New(ctx, db.ConnectionString())
+	if err != nil {
+		db.drop(ctx) //nolint:errcheck
+		return nil, err
+	}
+	db.pool = pool
+	return db, nil
+}
+
+// templateName returns the name of the database NewIsolatedDB copies.
+func (c *Container) templateName() string {
+	return "testground_template_" + c.cfg.database
+}
+
+// createTemplate copies the container's database into the template.
+func (c *Container) createTemplate(ctx context.Context, conn *pgx.Conn) error {
+	if err := c.copyDatabase(ctx, conn, c.cfg.database, c.templateName()); err != nil {
+		return fmt.Errorf("create template: %w", err)
+	}
+	return nil
+}
+
+// dropTemplate drops the template of NewIsolatedDB, if any, so the next call
+// takes a new one.
+func (c *Container) dropTemplate(ctx context.Context, conn *pgx.Conn) error {
+	c.templateMu.Lock()
+	defer c.templateMu.Unlock()
+	if _, err := conn.Exec(ctx, "DROP DATABASE IF EXISTS "+pgx.Identifier{c.templateName()}.Sanitize()); err != nil {
+		return fmt.Errorf("drop template: %w", err)
+	}
+	c.template = false
+	return nil
+}
+
+// Name returns the name of the database.
+func (db *IsolatedDB) Name() string {
+	return db.name
+}
+
+// ConnectionString returns the connection string for the database from test
+// code on the host.
+func (db *IsolatedDB) ConnectionString() string {
+	return db.c.connectionString(db.name)
+}
+
+// NetworkConnectionString returns the connection string for the database
+// from containers on the network set with WithNetwork, e.g. to start a
+// service per test.
+func (db *IsolatedDB) NetworkConnectionString() string {
+	return db.c.networkConnectionString(db.name)
+}
+
+// Pool returns a connection pool to the database. It is closed in t.Cleanup.
+func (db *IsolatedDB) Pool() *pgxpool.Pool {
+	return db.pool
+}
+
+// drop closes the pool and drops the database, terminating any connections
+// left open.
+func (db *IsolatedDB) drop(ctx context.Context) error {
+	if db.pool != nil {
+		db.pool.Close()
+	}
+	conn, err := db.c.maintenanceConn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+	return dropDatabase(ctx, conn, db.name)
+}
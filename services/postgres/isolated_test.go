@@ -0,0 +1,82 @@
+package postgres_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dsvdev/testground"
+	"github.com/dsvdev/testground/services/postgres"
+)
+
+func TestNewIsolatedDB(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	pg, err := postgres.New(ctx)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { pg.Terminate(context.Background()) })
+
+	testground.Apply(t, pg.Migrate("testdata/migrations"))
+	// Taking the template must not break a pool that is already in use.
+	shared, err := pg.Pool(ctx)
+	if err != nil {
+		t.Fatalf("Pool() error = %v", err)
+	}
+
+	var (
+		mu    sync.Mutex
+		names []string
+	)
+	t.Run("group", func(t *testing.T) {
+		for i := range 3 {
+			t.Run(fmt.Sprint(i), func(t *testing.T) {
+				t.Parallel()
+				db := pg.NewIsolatedDB(t)
+				mu.Lock()
+				names = append(names, db.Name())
+				mu.Unlock()
+
+				pool := db.Pool()
+				if _, err := pool.Exec(ctx, `INSERT INTO users (name) VALUES ('Alice')`); err != nil {
+					t.Fatalf("INSERT error = %v", err)
+				}
+				var n int
+				if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM users").Scan(&n); err != nil {
+					t.Fatalf("SELECT COUNT(*) error = %v", err)
+				}
+				if n != 1 {
+					t.Errorf("count = %d, want 1: the database is shared", n)
+				}
+			})
+		}
+	})
+
+	// The isolated databases are dropped and the container's database is
+	// untouched.
+	pool, err := pg.Pool(ctx)
+	if err != nil {
+		t.Fatalf("Pool() error = %v", err)
+	}
+	if pool != shared {
+		t.Error("Pool() returned a new pool after NewIsolatedDB")
+	}
+	var left int
+	if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM pg_database WHERE datname = ANY($1)", names).Scan(&left); err != nil {
+		t.Fatalf("SELECT pg_database error = %v", err)
+	}
+	if left != 0 {
+		t.Errorf("%d isolated databases left after cleanup, want 0", left)
+	}
+	var n int
+	if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM users").Scan(&n); err != nil {
+		t.Fatalf("SELECT COUNT(*) error = %v", err)
+	}
+	if n != 0 {
+		t.Errorf("count in the container's database = %d, want 0", n)
+	}
+}
@@ -0,0 +1,322 @@
+package postgres
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// migrationsTable records the versions applied by Migrate.
+const migrationsTable = "testground_schema_migrations"
+
+// migrationsLock is the advisory lock key that serializes Migrate and
+// MigrateDown across parallel tests sharing a database.
+const migrationsLock = 0x7465737467726e64
+
+// migration is one versioned schema change.
+type migration struct {
+	version int64
+	name    string
+	up      string
+	down    string
+	hasUp   bool
+	hasDown bool
+	// tx reports whether the migration runs inside a transaction. goose
+	// migrations do unless annotated with NO TRANSACTION; golang-migrate
+	// files are executed as they are, like golang-migrate does.
+	tx bool
+}
+
+// Migrator applies or reverts versioned migrations, returned by Migrate and
+// MigrateDown. It is a testground.Precondition; Run applies it outside a
+// test, e.g. in TestMain.
+type Migrator struct {
+	container *Container
+	fsys      fs.FS
+	down      bool
+}
+
+// Migrate returns a Migrator that applies the versioned migrations in
+// dir that have not been applied yet, in version order, and records each
+// applied version in the testground_schema_migrations table. Two layouts
+// are supported, even mixed in one directory:
+//
+//   - golang-migrate: 1_create_users.up.sql and 1_create_users.down.sql
+//   - goose: 00001_create_users.sql with -- +goose Up and -- +goose Down
+//     sections
+//
+// Use it with testground.Apply in a test, or call Run from TestMain.
+func (c *Container) Migrate(dir string) *Migrator {
+	return c.MigrateFS(os.DirFS(dir))
+}
+
+// MigrateFS is like Migrate but reads the migrations from the root of fsys,
+// e.g. an embed.FS shared with the production binary. Use fs.Sub for a
+// subdirectory.
+func (c *Container) MigrateFS(fsys fs.FS) *Migrator {
+	return &Migrator{container: c, fsys: fsys}
+}
+
+// MigrateDown returns a Migrator that reverts every migration from dir
+// recorded as applied, newest first, using the down migrations.
+func (c *Container) MigrateDown(dir string) *Migrator {
+	return c.MigrateDownFS(os.DirFS(dir))
+}
+
+// MigrateDownFS is like MigrateDown but reads the migrations from fsys.
+func (c *Container) MigrateDownFS(fsys fs.FS) *Migrator {
+	return &Migrator{container: c, fsys: fsys, down: true}
+}
+
+// Apply implements testground.Precondition; see Run.
+func (p *Migrator) Apply(ctx context.Context, t *testing.T) error {
+	return p.Run(ctx)
+}
+
+// Run applies or reverts the migrations.
+func (p *Migrator) Run(ctx context.Context) error {
+	migrations, err := parseMigrations(p.fsys)
+	if err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+
+	pool, err := p.container.Pool(ctx)
+	if err != nil {
+		return fmt.Errorf("migrate: connect: %w", err)
+	}
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("migrate: connect: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", int64(migrationsLock)); err != nil {
+		return fmt.Errorf("migrate: lock: %w", err)
+	}
+	defer conn.Exec(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", int64(migrationsLock)) //nolint:errcheck
+
+	if _, err := conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS `+migrationsTable+` (
+		version    BIGINT PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`); err != nil {
+		return fmt.Errorf("migrate: create %s: %w", migrationsTable, err)
+	}
+	applied, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+
+	if p.down {
+		slices.Reverse(migrations)
+	}
+	for _, m := range migrations {
+		if applied[m.version] == p.down {
+			if err := runMigration(ctx, conn, m, p.down); err != nil {
+				return fmt.Errorf("migrate: %d_%s: %w", m.version, m.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// appliedVersions returns the versions recorded in migrationsTable.
+func appliedVersions(ctx context.Context, conn *pgxpool.Conn) (map[int64]bool, error) {
+	rows, err := conn.Query(ctx, "SELECT version FROM "+migrationsTable)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]bool, len(versions))
+	for _, v := range versions {
+		applied[v] = true
+	}
+	return applied, nil
+}
+
+// runMigration applies m, or reverts it if down is set, and updates
+// migrationsTable accordingly.
+func runMigration(ctx context.Context, conn *pgxpool.Conn, m migration, down bool) error {
+	sql, record, args := m.up, "INSERT INTO "+migrationsTable+" (version, name) VALUES ($1, $2)", []any{m.version, m.name}
+	if down {
+		if !m.hasDown {
+			return errors.New("no down migration")
+		}
+		sql, record, args = m.down, "DELETE FROM "+migrationsTable+" WHERE version = $1", []any{m.version}
+	}
+
+	if !m.tx {
+		// Without arguments Exec uses the simple protocol, which accepts
+		// several statements.
+		if strings.TrimSpace(sql) != "" {
+			if _, err := conn.Exec(ctx, sql); err != nil {
+				return err
+			}
+		}
+		_, err := conn.Exec(ctx, record, args...)
+		return err
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.WithoutCancel(ctx)) //nolint:errcheck
+	if strings.TrimSpace(sql) != "" {
+		if _, err := tx.Exec(ctx, sql); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// parseMigrations reads the migrations at the root of fsys, sorted by
+// version. Files that are not migrations are ignored.
+func parseMigrations(fsys fs.FS) ([]migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*migration)
+	get := func(file string, version int64, name string) (*migration, error) {
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{version: version, name: name}
+			byVersion[version] = m
+		} else if m.name != name {
+			return nil, fmt.Errorf("%s: version %d is already used by %s", file, version, m.name)
+		}
+		return m, nil
+	}
+
+	for _, e := range entries {
+		file := e.Name()
+		if e.IsDir() || !strings.HasSuffix(file, ".sql") {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		switch base := strings.TrimSuffix(file, ".sql"); {
+		case strings.HasSuffix(base, ".up"), strings.HasSuffix(base, ".down"):
+			stem, direction := splitExt(base)
+			version, name, err := parseVersion(stem)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+			m, err := get(file, version, name)
+			if err != nil {
+				return nil, err
+			}
+			if direction == "up" {
+				if m.hasUp {
+					return nil, fmt.Errorf("%s: duplicate up migration for version %d", file, version)
+				}
+				m.up, m.hasUp = string(data), true
+			} else {
+				if m.hasDown {
+					return nil, fmt.Errorf("%s: duplicate down migration for version %d", file, version)
+				}
+				m.down, m.hasDown = string(data), true
+			}
+		default:
+			version, name, err := parseVersion(base)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+			if _, ok := byVersion[version]; ok {
+				return nil, fmt.Errorf("%s: version %d is already used by %s", file, version, byVersion[version].name)
+			}
+			m, err := parseGoose(string(data))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+			m.version, m.name = version, name
+			byVersion[version] = &m
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		// Applying it would record the version without changing anything.
+		if !m.hasUp {
+			return nil, fmt.Errorf("version %d (%s) has a down migration but no up migration", m.version, m.name)
+		}
+		migrations = append(migrations, *m)
+	}
+	slices.SortFunc(migrations, func(a, b migration) int {
+		return cmp.Compare(a.version, b.version)
+	})
+	return migrations, nil
+}
+
+// parseVersion splits "0001_create_users" into its version and name.
+func parseVersion(stem string) (int64, string, error) {
+	digits, name, _ := strings.Cut(stem, "_")
+	version, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, "", errors.New("file name does not start with a version number")
+	}
+	return version, name, nil
+}
+
+// splitExt splits "1_init.up" into "1_init" and "up".
+func splitExt(name string) (string, string) {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext), strings.TrimPrefix(ext, ".")
+}
+
+// parseGoose splits a goose SQL migration into its up and down sections.
+// StatementBegin and StatementEnd annotations are dropped: the sections are
+// executed as a whole, so statements need no splitting.
+func parseGoose(src string) (migration, error) {
+	m := migration{tx: true}
+	var up, down strings.Builder
+	var section *strings.Builder
+	seenUp := false
+	for line := range strings.Lines(src) {
+		annotation, ok := strings.CutPrefix(strings.TrimSpace(line), "-- +goose ")
+		if !ok {
+			if section != nil {
+				section.WriteString(line)
+			}
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(annotation)) {
+		case "up":
+			section, seenUp = &up, true
+		case "down":
+			section, m.hasDown = &down, true
+		case "no transaction":
+			m.tx = false
+		case "statementbegin", "statementend":
+		default:
+			return migration{}, fmt.Errorf("unsupported annotation %q", strings.TrimSpace(line))
+		}
+	}
+	if !seenUp {
+		return migration{}, errors.New("missing -- +goose Up annotation")
+	}
+	m.up, m.down, m.hasUp = up.String(), down.String(), true
+	return m, nil
+}
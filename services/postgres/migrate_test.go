@@ -0,0 +1,115 @@
+package postgres
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"2_add_email.up.sql":    {Data: []byte("ALTER TABLE users ADD email TEXT;")},
+		"2_add_email.down.sql":  {Data: []byte("ALTER TABLE users DROP email;")},
+		"1_create_users.up.sql": {Data: []byte("CREATE TABLE users (id BIGINT);")},
+		"00003_index.sql": {Data: []byte(`-- +goose NO TRANSACTION
+-- +goose Up
+CREATE INDEX CONCURRENTLY users_email ON users (email);
+
+-- +goose Down
+DROP INDEX users_email;
+`)},
+		"README.md":     {Data: []byte("not a migration")},
+		"seed/1_x.sql":  {Data: []byte("ignored")},
+		"embed_test.go": {Data: []byte("package x")},
+	}
+
+	got, err := parseMigrations(fsys)
+	if err != nil {
+		t.Fatalf("parseMigrations() error = %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("parseMigrations() returned %d migrations, want 3: %+v", len(got), got)
+	}
+	for i, want := range []struct {
+		version int64
+		name    string
+		hasDown bool
+		tx      bool
+	}{
+		{1, "create_users", false, false},
+		{2, "add_email", true, false},
+		{3, "index", true, false},
+	} {
+		m := got[i]
+		if m.version != want.version || m.name != want.name || m.hasDown != want.hasDown || m.tx != want.tx {
+			t.Errorf("migration %d = {%d %q hasDown=%v tx=%v}, want %+v", i, m.version, m.name, m.hasDown, m.tx, want)
+		}
+	}
+	if strings.TrimSpace(got[2].up) != "CREATE INDEX CONCURRENTLY users_email ON users (email);" {
+		t.Errorf("goose up = %q", got[2].up)
+	}
+	if strings.TrimSpace(got[2].down) != "DROP INDEX users_email;" {
+		t.Errorf("goose down = %q", got[2].down)
+	}
+}
+
+func TestParseGoose(t *testing.T) {
+	m, err := parseGoose(`-- +goose Up
+-- +goose StatementBegin
+CREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql;
+-- +goose StatementEnd
+`)
+	if err != nil {
+		t.Fatalf("parseGoose() error = %v", err)
+	}
+	if !m.tx || m.hasDown {
+		t.Errorf("parseGoose() tx = %v, hasDown = %v, want true, false", m.tx, m.hasDown)
+	}
+	if strings.Contains(m.up, "+goose") || !strings.Contains(m.up, "CREATE FUNCTION") {
+		t.Errorf("parseGoose() up = %q", m.up)
+	}
+}
+
+func TestParseMigrations_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+		want string
+	}{
+		{
+			name: "duplicate version",
+			fsys: fstest.MapFS{
+				"1_a.up.sql": {Data: []byte("SELECT 1")},
+				"1_b.up.sql": {Data: []byte("SELECT 1")},
+			},
+			want: "version 1 is already used",
+		},
+		{
+			name: "no version",
+			fsys: fstest.MapFS{"init.up.sql": {Data: []byte("SELECT 1")}},
+			want: "does not start with a version number",
+		},
+		{
+			name: "down without up",
+			fsys: fstest.MapFS{
+				"1_a.up.sql":   {Data: []byte("SELECT 1")},
+				"2_b.down.sql": {Data: []byte("SELECT 1")},
+			},
+			want: "version 2 (b) has a down migration but no up migration",
+		},
+		{
+			name: "goose without up",
+			fsys: fstest.MapFS{"1_a.sql": {Data: []byte("SELECT 1")}},
+			want: "missing -- +goose Up",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseMigrations(tt.fsys)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parseMigrations() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -10,6 +10,13 @@ type config struct {
 	port         string
 	networkName  string
 	networkAlias string
+	reuseName    string
+	image        string
+	extensions   []string
+	initScripts  string
+	settings     map[string]string
+	tmpfs        bool
+	replicas     int
 }
 
 func defaultConfig() config {
@@ -24,6 +31,8 @@ func defaultConfig() config {
 
 type Option func(*config)
 
+// WithVersion sets the tag of the official postgres image. Default: "16".
+// It is ignored with WithImage.
 func WithVersion(v string) Option {
 	return func(c *config) {
 		c.version = v
@@ -65,3 +74,78 @@ func WithNetworkAlias(alias string) Option {
 		c.networkAlias = alias
 	}
 }
+
+// WithReuse keeps the container running after Terminate and attaches to it on
+// the next run instead of starting a new one, as long as the configuration is
+// unchanged. The database is dropped and recreated on attach, see Reset.
+//
+// Cross-run reuse requires TESTCONTAINERS_RYUK_DISABLED=true; otherwise the
+// testcontainers reaper removes the container when the test binary exits.
+func WithReuse(name string) Option {
+	return func(c *config) {
+		c.reuseName = name
+	}
+}
+
+// WithImage runs ref instead of the official postgres image, e.g.
+// "postgis/postgis:16-3.4" or "pgvector/pgvector:pg16". The image must accept
+// the POSTGRES_* variables and init scripts of the official one.
+func WithImage(ref string) Option {
+	return func(c *config) {
+		c.image = ref
+	}
+}
+
+// WithExtensions creates the extensions in the database once the server is
+// up, e.g. "pg_trgm", "postgis" or "vector". Extensions that are not part of
+// the official image need WithImage. Reset creates them again.
+func WithExtensions(names ...string) Option {
+	return func(c *config) {
+		c.extensions = append(c.extensions, names...)
+	}
+}
+
+// WithInitScripts copies the *.sql, *.sql.gz and *.sh files in dir into
+// /docker-entrypoint-initdb.d, where the image runs them in name order when
+// it initializes the database. Reset does not run them again.
+func WithInitScripts(dir string) Option {
+	return func(c *config) {
+		c.initScripts = dir
+	}
+}
+
+// WithConfig sets a server setting, passed as postgres -c key=value, e.g.
+// WithConfig("max_connections", "500") or WithConfig("wal_level", "logical").
+// It can be given more than once.
+func WithConfig(key, value string) Option {
+	return func(c *config) {
+		if c.settings == nil {
+			c.settings = make(map[string]string)
+		}
+		c.settings[key] = value
+	}
+}
+
+// WithTmpfs keeps the data directory in memory, which speeds up write-heavy
+// tests. The data does not survive Stop: the server starts with a fresh
+// database. Combine with WithConfig("fsync", "off") for more speed.
+func WithTmpfs() Option {
+	return func(c *config) {
+		c.tmpfs = true
+	}
+}
+
+// WithLogicalReplication starts the server with wal_level=logical, which
+// CaptureChanges and external CDC tools such as Debezium need. Without it
+// CaptureChanges fails the test.
+func WithLogicalReplication() Option {
+	return WithConfig("wal_level", "logical")
+}
+
+// WithReplicas sets the number of streaming replicas NewCluster starts next to
+// the primary. Default: 1. New ignores it.
+func WithReplicas(n int) Option {
+	return func(c *config) {
+		c.replicas = n
+	}
+}
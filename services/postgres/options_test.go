@@ -0,0 +1,18 @@
+package postgres
+
+import (
+	"testing"
+)
+
+func TestInitScriptFiles(t *testing.T) {
+	files, err := initScriptFiles("testdata/init")
+	if err != nil {
+		t.Fatalf("initScriptFiles() error = %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("initScriptFiles() returned %d files, want 1: %+v", len(files), files)
+	}
+	if got, want := files[0].ContainerFilePath, "/docker-entrypoint-initdb.d/01_marker.sql"; got != want {
+		t.Errorf("ContainerFilePath = %q, want %q", got, want)
+	}
+}
@@ -3,13 +3,22 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/wait"
 
+	"github.com/dsvdev/testground"
 	"github.com/dsvdev/testground/internal/container"
 )
 
@@ -18,6 +27,15 @@ type Container struct {
 	cfg    config
 	poolMu sync.Mutex
 	pool   *pgxpool.Pool
+
+	// templateMu serializes NewIsolatedDB, which copies the template, and
+	// guards template.
+	templateMu sync.Mutex
+	template   bool
+
+	// captureMu guards captures, the number of active CaptureQueries.
+	captureMu sync.Mutex
+	captures  int
 }
 
 func New(ctx context.Context, opts ...Option) (*Container, error) {
@@ -25,14 +43,19 @@ func New(ctx context.Context, opts ...Option) (*Container, error) {
 	for _, opt := range opts {
 		opt(&cfg)
 	}
+	return start(ctx, cfg, nil)
+}
 
-	exposedPort := "5432/tcp"
-	if cfg.port != "" {
-		exposedPort = fmt.Sprintf("%s:5432/tcp", cfg.port)
+// start starts a server configured by cfg. modify, if not nil, adjusts the
+// request before the container is created, e.g. to join a cluster network.
+func start(ctx context.Context, cfg config, modify func(*testcontainers.ContainerRequest)) (*Container, error) {
+	exposedPort, err := exposedPort(cfg.port)
+	if err != nil {
+		return nil, err
 	}
 
 	req := testcontainers.ContainerRequest{
-		Image:        fmt.Sprintf("postgres:%s", cfg.version),
+		Image:        cfg.imageRef(),
 		ExposedPorts: []string{exposedPort},
 		Env: map[string]string{
 			"POSTGRES_DB":       cfg.database,
@@ -46,6 +69,22 @@ func New(ctx context.Context, opts ...Option) (*Container, error) {
 		),
 	}
 
+	if len(cfg.settings) > 0 {
+		req.Cmd = append([]string{"postgres"}, cfg.serverArgs()...)
+	}
+	if cfg.initScripts != "" {
+		files, err := initScriptFiles(cfg.initScripts)
+		if err != nil {
+			return nil, fmt.Errorf("init scripts: %w", err)
+		}
+		req.Files = files
+	}
+	if cfg.tmpfs {
+		// Set PGDATA explicitly: the default differs between major versions.
+		req.Env["PGDATA"] = tmpfsDataDir
+		req.Tmpfs = map[string]string{tmpfsDataDir: "rw"}
+	}
+
 	if cfg.networkName != "" {
 		req.Networks = []string{cfg.networkName}
 		if cfg.networkAlias != "" {
@@ -55,25 +94,139 @@ func New(ctx context.Context, opts ...Option) (*Container, error) {
 		}
 	}
 
-	base, err := container.Start(ctx, req, "5432")
+	if modify != nil {
+		modify(&req)
+	}
+
+	var startOpts []container.Option
+	if cfg.reuseName != "" {
+		startOpts = append(startOpts, container.WithReuse("postgres-"+cfg.reuseName, container.ConfigHash(cfg)))
+	}
+
+	base, err := container.Start(ctx, req, "5432", startOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Container{base: base, cfg: cfg}
+	if base.Reused() {
+		// A run that crashed during CaptureQueries left statement logging
+		// enabled in postgresql.auto.conf.
+		if err := c.resetStatementLogging(ctx); err != nil {
+			return nil, fmt.Errorf("reset reused container: %w", err)
+		}
+		if err := c.Reset(ctx); err != nil {
+			return nil, fmt.Errorf("reset reused container: %w", err)
+		}
+	} else if err := c.createExtensions(ctx); err != nil {
+		c.Terminate(ctx) //nolint:errcheck
+		return nil, err
+	}
+	return c, nil
+}
+
+// exposedPort binds the server port to hostPort, or to a free port if
+// hostPort is empty. The binding is explicit even then: Docker keeps explicit
+// bindings across Stop and Start, so ConnectionString and the pool stay
+// valid after a restart.
+func exposedPort(hostPort string) (string, error) {
+	if hostPort == "" {
+		p, err := container.FreePort()
+		if err != nil {
+			return "", fmt.Errorf("find free port: %w", err)
+		}
+		hostPort = fmt.Sprint(p)
+	}
+	return fmt.Sprintf("%s:5432/tcp", hostPort), nil
+}
+
+// imageRef returns the image to run.
+func (c config) imageRef() string {
+	if c.image != "" {
+		return c.image
+	}
+	return fmt.Sprintf("postgres:%s", c.version)
+}
+
+// serverArgs returns the settings of WithConfig as postgres arguments.
+func (c config) serverArgs() []string {
+	var args []string
+	for _, key := range slices.Sorted(maps.Keys(c.settings)) {
+		args = append(args, "-c", key+"="+c.settings[key])
+	}
+	return args
+}
+
+// tmpfsDataDir is the data directory with WithTmpfs.
+const tmpfsDataDir = "/var/lib/postgresql/data"
+
+// initScriptFiles lists the files in dir that the image runs on
+// initialization, to be copied into /docker-entrypoint-initdb.d.
+func initScriptFiles(dir string) ([]testcontainers.ContainerFile, error) {
+	entries, err := os.ReadDir(dir)
 	if err != nil {
 		return nil, err
 	}
+	var files []testcontainers.ContainerFile
+	for _, e := range entries {
+		name := e.Name()
+		mode := int64(0o644)
+		switch {
+		case e.IsDir():
+			continue
+		case strings.HasSuffix(name, ".sh"):
+			mode = 0o755
+		case strings.HasSuffix(name, ".sql"), strings.HasSuffix(name, ".sql.gz"),
+			strings.HasSuffix(name, ".sql.xz"), strings.HasSuffix(name, ".sql.zst"):
+		default:
+			continue
+		}
+		files = append(files, testcontainers.ContainerFile{
+			HostFilePath:      filepath.Join(dir, name),
+			ContainerFilePath: "/docker-entrypoint-initdb.d/" + name,
+			FileMode:          mode,
+		})
+	}
+	return files, nil
+}
 
-	return &Container{base: base, cfg: cfg}, nil
+// createExtensions creates the extensions set with WithExtensions.
+func (c *Container) createExtensions(ctx context.Context) error {
+	if len(c.cfg.extensions) == 0 {
+		return nil
+	}
+	conn, err := pgx.Connect(ctx, c.ConnectionString())
+	if err != nil {
+		return fmt.Errorf("create extensions: connect: %w", err)
+	}
+	defer conn.Close(ctx)
+	for _, ext := range c.cfg.extensions {
+		if _, err := conn.Exec(ctx, "CREATE EXTENSION IF NOT EXISTS "+pgx.Identifier{ext}.Sanitize()); err != nil {
+			return fmt.Errorf("create extension %s: %w", ext, err)
+		}
+	}
+	return nil
 }
 
 func (c *Container) ConnectionString() string {
+	return c.connectionString(c.cfg.database)
+}
+
+func (c *Container) connectionString(database string) string {
 	return fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
 		c.cfg.user,
 		c.cfg.password,
 		c.base.Host(),
 		c.base.Port(),
-		c.cfg.database,
+		database,
 	)
 }
 
 func (c *Container) NetworkConnectionString() string {
+	return c.networkConnectionString(c.cfg.database)
+}
+
+func (c *Container) networkConnectionString(database string) string {
 	host := c.cfg.networkAlias
 	if host == "" {
 		host = c.base.Host()
@@ -82,7 +235,7 @@ func (c *Container) NetworkConnectionString() string {
 		c.cfg.user,
 		c.cfg.password,
 		host,
-		c.cfg.database,
+		database,
 	)
 }
 
@@ -106,7 +259,228 @@ func (c *Container) Pool(ctx context.Context) (*pgxpool.Pool, error) {
 	return c.pool, nil
 }
 
+// ContainerID returns the Docker ID of the PostgreSQL container, e.g. for
+// testground.Network.Disconnect.
+func (c *Container) ContainerID() string {
+	return c.base.ID()
+}
+
+// Logs returns the server log produced so far. The caller must close the
+// returned reader.
+func (c *Container) Logs(ctx context.Context) (io.ReadCloser, error) {
+	return c.base.Logs(ctx)
+}
+
+// LogsSince returns the server log produced since the Docker engine time
+// since. The caller must close the returned reader.
+func (c *Container) LogsSince(ctx context.Context, since time.Time) (io.ReadCloser, error) {
+	return c.base.LogsSince(ctx, since)
+}
+
+// ExecCommand runs cmd inside the PostgreSQL container and returns its exit
+// code, stdout and stderr. A non-zero exit code is not an error. It is the
+// counterpart of Exec on the other containers; here Exec is the SQL
+// precondition.
+func (c *Container) ExecCommand(ctx context.Context, cmd []string) (exitCode int, stdout, stderr string, err error) {
+	return c.base.Exec(ctx, cmd)
+}
+
+// CopyFileTo copies the file at hostPath into the PostgreSQL container.
+func (c *Container) CopyFileTo(ctx context.Context, hostPath, containerPath string) error {
+	return c.base.CopyFileTo(ctx, hostPath, containerPath)
+}
+
+// CopyFileFrom copies a file out of the PostgreSQL container to hostPath.
+func (c *Container) CopyFileFrom(ctx context.Context, containerPath, hostPath string) error {
+	return c.base.CopyFileFrom(ctx, containerPath, hostPath)
+}
+
+// Stop stops the PostgreSQL server without removing the container; data is
+// kept. Connections fail until Start.
+func (c *Container) Stop(ctx context.Context) error {
+	return c.base.Stop(ctx, nil)
+}
+
+// Start starts a server stopped with Stop and waits until it accepts
+// connections again. Connections the pool held before the stop are discarded,
+// so the pool returned by Pool reconnects on next use.
+func (c *Container) Start(ctx context.Context) error {
+	if err := c.base.Start(ctx); err != nil {
+		return err
+	}
+	// After a restart the wait strategy matches the log lines of the previous
+	// start, so it cannot be relied upon.
+	if err := testground.WaitHealthy(ctx, c); err != nil {
+		return fmt.Errorf("wait for postgres: %w", err)
+	}
+	c.resetPool()
+	return nil
+}
+
+// Restart stops and starts the server, see Stop and Start.
+func (c *Container) Restart(ctx context.Context) error {
+	if err := c.Stop(ctx); err != nil {
+		return err
+	}
+	return c.Start(ctx)
+}
+
+// Pause freezes the server: connections stay open but queries hang until
+// Unpause.
+func (c *Container) Pause(ctx context.Context) error {
+	return c.base.Pause(ctx)
+}
+
+// Unpause resumes a server frozen with Pause.
+func (c *Container) Unpause(ctx context.Context) error {
+	return c.base.Unpause(ctx)
+}
+
+// Healthy reports whether the server accepts connections to the database.
+func (c *Container) Healthy(ctx context.Context) error {
+	conn, err := pgx.Connect(ctx, c.ConnectionString())
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+	return conn.Ping(ctx)
+}
+
+// Reset drops the database and creates it again, empty. Open connections to
+// it are terminated and the pool returned by Pool is closed; the next call to
+// Pool creates a new one. New calls Reset automatically when WithReuse
+// attaches to a container from an earlier run.
+func (c *Container) Reset(ctx context.Context) error {
+	c.closePool()
+
+	conn, err := c.maintenanceConn(ctx)
+	if err != nil {
+		return fmt.Errorf("reset: connect: %w", err)
+	}
+	defer conn.Close(ctx)
+
+	// The template of NewIsolatedDB is a copy of the old database.
+	if err := c.dropTemplate(ctx, conn); err != nil {
+		return fmt.Errorf("reset: %w", err)
+	}
+
+	// A database cannot be used as a template while someone is connected to
+	// it, so the new one is created from a template that nobody uses.
+	template := "template1"
+	if c.cfg.database == "postgres" {
+		template = "template0"
+	}
+	// Slots of CaptureChanges left behind by a crashed run keep the database
+	// from being dropped.
+	if _, err := conn.Exec(ctx,
+		"SELECT pg_drop_replication_slot(slot_name) FROM pg_replication_slots WHERE starts_with(slot_name, $1)",
+		cdcSlotPrefix); err != nil {
+		return fmt.Errorf("reset: drop replication slots: %w", err)
+	}
+	if err := dropDatabase(ctx, conn, c.cfg.database); err != nil {
+		return fmt.Errorf("reset: drop database: %w", err)
+	}
+	db := pgx.Identifier{c.cfg.database}.Sanitize()
+	if _, err := conn.Exec(ctx, fmt.Sprintf("CREATE DATABASE %s TEMPLATE %s", db, template)); err != nil {
+		return fmt.Errorf("reset: create database: %w", err)
+	}
+	if err := c.createExtensions(ctx); err != nil {
+		return fmt.Errorf("reset: %w", err)
+	}
+	return nil
+}
+
+// serverVersion returns the server_version_num of the server conn is
+// connected to, e.g. 160004 for 16.4.
+func serverVersion(ctx context.Context, conn *pgx.Conn) (int, error) {
+	var v string
+	if err := conn.QueryRow(ctx, "SHOW server_version_num").Scan(&v); err != nil {
+		return 0, fmt.Errorf("server version: %w", err)
+	}
+	return strconv.Atoi(v)
+}
+
+// forceDropVersion is the first server version that supports
+// DROP DATABASE ... WITH (FORCE).
+const forceDropVersion = 130000
+
+// dropDatabase drops database if it exists, terminating the sessions
+// connected to it. Before PostgreSQL 13, which added WITH (FORCE), it stops
+// new connections first, so that a pool cannot reconnect in between, and
+// then terminates the open ones; DROP DATABASE waits a few seconds for them
+// to exit.
+func dropDatabase(ctx context.Context, conn *pgx.Conn, database string) error {
+	v, err := serverVersion(ctx, conn)
+	if err != nil {
+		return err
+	}
+	db := pgx.Identifier{database}.Sanitize()
+	if v >= forceDropVersion {
+		_, err := conn.Exec(ctx, fmt.Sprintf("DROP DATABASE IF EXISTS %s WITH (FORCE)", db))
+		return err
+	}
+
+	var exists bool
+	if err := conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)", database).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return nil
+	}
+	if _, err := conn.Exec(ctx, fmt.Sprintf("ALTER DATABASE %s ALLOW_CONNECTIONS false", db)); err != nil {
+		return err
+	}
+	if _, err := conn.Exec(ctx,
+		"SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname = $1 AND pid <> pg_backend_pid()",
+		database); err != nil {
+		return err
+	}
+	_, err = conn.Exec(ctx, fmt.Sprintf("DROP DATABASE %s", db))
+	return err
+}
+
+// maintenanceConn connects to a database other than the configured one, for
+// statements that cannot run over a connection to it, such as DROP DATABASE.
+func (c *Container) maintenanceConn(ctx context.Context) (*pgx.Conn, error) {
+	return pgx.Connect(ctx, c.connectionString(c.maintenanceDatabase()))
+}
+
+func (c *Container) maintenanceDatabase() string {
+	if c.cfg.database == "postgres" {
+		return "template1"
+	}
+	return "postgres"
+}
+
+// appConn connects to database as application app, so that the connection
+// can be told apart in pg_stat_activity and the server log.
+func (c *Container) appConn(ctx context.Context, database, app string) (*pgx.Conn, error) {
+	cfg, err := pgx.ParseConfig(c.connectionString(database))
+	if err != nil {
+		return nil, err
+	}
+	cfg.RuntimeParams["application_name"] = app
+	return pgx.ConnectConfig(ctx, cfg)
+}
+
 func (c *Container) Terminate(ctx context.Context) error {
+	c.closePool()
+	return c.base.Terminate(ctx)
+}
+
+// resetPool discards the connections of the pool returned by Pool after the
+// server terminated them. The pool itself stays usable: idle connections are
+// closed at once, acquired ones when they are released, and new ones are
+// opened on demand.
+func (c *Container) resetPool() {
+	c.poolMu.Lock()
+	defer c.poolMu.Unlock()
+	if c.pool != nil {
+		c.pool.Reset()
+	}
+}
+
+func (c *Container) closePool() {
 	c.poolMu.Lock()
 	pool := c.pool
 	c.pool = nil
@@ -114,5 +488,4 @@ func (c *Container) Terminate(ctx context.Context) error {
 	if pool != nil {
 		pool.Close()
 	}
-	return c.base.Terminate(ctx)
 }
@@ -2,11 +2,16 @@ package postgres_test
 
 import (
 	"context"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/jackc/pgx/v5"
 
+	"github.com/dsvdev/testground"
+	"github.com/dsvdev/testground/faker"
 	"github.com/dsvdev/testground/services/postgres"
 )
 
@@ -113,3 +118,313 @@ func TestPostgresContainer_RandomPorts(t *testing.T) {
 		t.Errorf("got r1=%d, r2=%d, want both 1", r1, r2)
 	}
 }
+
+func TestPostgresContainer_Reuse(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	defer cancel()
+
+	reuseName := "test-" + faker.RandomString(8)
+
+	first, err := postgres.New(ctx, postgres.WithReuse(reuseName))
+	if err != nil {
+		t.Fatalf("New() first error = %v", err)
+	}
+	testground.Apply(t, first.Exec(`CREATE TABLE leftovers (id INT)`))
+	if err := first.Terminate(ctx); err != nil {
+		t.Fatalf("Terminate() error = %v", err)
+	}
+
+	second, err := postgres.New(ctx, postgres.WithReuse(reuseName))
+	if err != nil {
+		t.Fatalf("New() second error = %v", err)
+	}
+	t.Cleanup(func() { second.Terminate(context.Background()) })
+
+	if first.ConnectionString() != second.ConnectionString() {
+		t.Errorf("second run did not attach to the same container: %s vs %s",
+			first.ConnectionString(), second.ConnectionString())
+	}
+
+	conn, err := second.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Conn() error = %v", err)
+	}
+	defer conn.Close(ctx)
+
+	var exists bool
+	if err := conn.QueryRow(ctx, `SELECT to_regclass('leftovers') IS NOT NULL`).Scan(&exists); err != nil {
+		t.Fatalf("to_regclass error = %v", err)
+	}
+	if exists {
+		t.Error("table from the previous run survived Reset")
+	}
+}
+
+func TestPostgresContainer_ExecAndCopy(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	pg, err := postgres.New(ctx)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { pg.Terminate(context.Background()) })
+
+	dir := t.TempDir()
+	script := filepath.Join(dir, "script.sql")
+	if err := os.WriteFile(script, []byte("CREATE TABLE scripted (id INT);\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := pg.CopyFileTo(ctx, script, "/tmp/script.sql"); err != nil {
+		t.Fatalf("CopyFileTo() error = %v", err)
+	}
+
+	code, stdout, stderr, err := pg.ExecCommand(ctx, []string{"psql", "-U", "test", "-d", "test", "-f", "/tmp/script.sql"})
+	if err != nil {
+		t.Fatalf("ExecCommand() error = %v", err)
+	}
+	if code != 0 {
+		t.Fatalf("psql exit code = %d, stderr: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "CREATE TABLE") {
+		t.Errorf("psql stdout = %q, want CREATE TABLE", stdout)
+	}
+
+	copied := filepath.Join(dir, "copied.sql")
+	if err := pg.CopyFileFrom(ctx, "/tmp/script.sql", copied); err != nil {
+		t.Fatalf("CopyFileFrom() error = %v", err)
+	}
+	data, err := os.ReadFile(copied)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if !strings.Contains(string(data), "scripted") {
+		t.Errorf("copied file = %q, want the original script", data)
+	}
+
+	code, _, _, err = pg.ExecCommand(ctx, []string{"psql", "-U", "test", "-d", "test", "-c", "SELECT * FROM missing"})
+	if err != nil {
+		t.Fatalf("ExecCommand() error = %v", err)
+	}
+	if code == 0 {
+		t.Error("psql exit code = 0 for a failing query, want non-zero")
+	}
+}
+
+func TestPostgresContainer_Restart(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	defer cancel()
+
+	container, err := postgres.New(ctx)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() {
+		container.Terminate(context.Background())
+	})
+
+	pool, err := container.Pool(ctx)
+	if err != nil {
+		t.Fatalf("Pool() error = %v", err)
+	}
+	if _, err := pool.Exec(ctx, "CREATE TABLE kept (id int)"); err != nil {
+		t.Fatalf("CREATE TABLE error = %v", err)
+	}
+	connStr := container.ConnectionString()
+
+	if err := container.Restart(ctx); err != nil {
+		t.Fatalf("Restart() error = %v", err)
+	}
+
+	if got := container.ConnectionString(); got != connStr {
+		t.Errorf("ConnectionString() = %q after restart, want %q", got, connStr)
+	}
+	// The same pool must work without the caller recreating it.
+	if _, err := pool.Exec(ctx, "INSERT INTO kept VALUES (1)"); err != nil {
+		t.Errorf("pool after restart: %v", err)
+	}
+}
+
+func TestPostgresContainer_Pause(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	defer cancel()
+
+	container, err := postgres.New(ctx)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() {
+		container.Terminate(context.Background())
+	})
+
+	pool, err := container.Pool(ctx)
+	if err != nil {
+		t.Fatalf("Pool() error = %v", err)
+	}
+
+	if err := container.Pause(ctx); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+	pausedCtx, pausedCancel := context.WithTimeout(ctx, time.Second)
+	defer pausedCancel()
+	if err := pool.Ping(pausedCtx); err == nil {
+		t.Error("Ping() succeeded against a paused server")
+	}
+
+	if err := container.Unpause(ctx); err != nil {
+		t.Fatalf("Unpause() error = %v", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		t.Errorf("Ping() after Unpause error = %v", err)
+	}
+}
+
+func TestPostgresContainer_Assertions(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	pg, err := postgres.New(ctx)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { pg.Terminate(context.Background()) })
+
+	testground.Apply(t,
+		pg.Migrate("testdata/migrations"),
+		pg.Exec(`INSERT INTO users (name, email) VALUES ('Alice', 'alice@example.com'), ('Bob', NULL)`),
+	)
+
+	pg.AssertRowCount(t, "users", "", 2)
+	pg.AssertRowCount(t, "users", "email IS NOT NULL", 1)
+	pg.AssertRowCount(t, "public.users", "name = $1", 1, "Bob")
+	pg.AssertRowExists(t, "users", map[string]any{"name": "Bob", "email": nil})
+	pg.AssertQueryReturns(t, "SELECT * FROM users ORDER BY id", []map[string]any{
+		{"id": 1, "name": "Alice"},
+		{"id": 2, "name": "Bob"},
+	})
+
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		pool, err := pg.Pool(context.Background())
+		if err == nil {
+			pool.Exec(context.Background(), `INSERT INTO users (name) VALUES ('Carol')`) //nolint:errcheck
+		}
+	}()
+	pg.Eventually(5*time.Second, 50*time.Millisecond).AssertRowExists(t, "users", map[string]any{"name": "Carol"})
+}
+
+func TestPostgresContainer_ServerOptions(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	pg, err := postgres.New(ctx,
+		postgres.WithExtensions("pg_trgm"),
+		postgres.WithInitScripts("testdata/init"),
+		postgres.WithConfig("max_connections", "42"),
+		postgres.WithTmpfs(),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { pg.Terminate(context.Background()) })
+
+	pg.AssertRowCount(t, "init_marker", "", 1)
+	pg.AssertRowExists(t, "pg_extension", map[string]any{"extname": "pg_trgm"})
+	pg.AssertQueryReturns(t, "SELECT current_setting('max_connections') AS max_connections", []map[string]any{
+		{"max_connections": "42"},
+	})
+
+	code, stdout, _, err := pg.ExecCommand(ctx, []string{"sh", "-c", "df -P $PGDATA | tail -1"})
+	if err != nil || code != 0 {
+		t.Fatalf("ExecCommand(df) = %d, %v", code, err)
+	}
+	if !strings.HasPrefix(stdout, "tmpfs") {
+		t.Errorf("data directory is on %q, want tmpfs", stdout)
+	}
+
+	// Reset recreates the extensions.
+	if err := pg.Reset(ctx); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	pg.AssertRowExists(t, "pg_extension", map[string]any{"extname": "pg_trgm"})
+}
+
+func TestPostgresContainer_CaptureQueries(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	pg, err := postgres.New(ctx)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { pg.Terminate(context.Background()) })
+
+	testground.Apply(t, pg.Migrate("testdata/migrations"))
+	pool, err := pg.Pool(ctx)
+	if err != nil {
+		t.Fatalf("Pool() error = %v", err)
+	}
+
+	rec := pg.CaptureQueries(t)
+	for id := range 3 {
+		var n int
+		if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM users WHERE id = $1", id).Scan(&n); err != nil {
+			t.Fatalf("SELECT error = %v", err)
+		}
+	}
+
+	rec.AssertQueryCount(t, `FROM users WHERE id = \$1`, 3)
+	rec.AssertNoSeqScan(t, "users")
+	rec.ExplainSlow(t, 0)
+
+	if got := len(rec.Queries(t)); got != 3 {
+		t.Errorf("len(Queries()) = %d, want 3", got)
+	}
+}
+
+func TestPostgresContainer_CaptureChanges(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	pg, err := postgres.New(ctx, postgres.WithLogicalReplication())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { pg.Terminate(context.Background()) })
+
+	testground.Apply(t,
+		pg.Migrate("testdata/migrations"),
+		pg.Exec(`ALTER TABLE users REPLICA IDENTITY FULL`),
+	)
+
+	cdc := pg.CaptureChanges(t, "users")
+	testground.Apply(t,
+		pg.Exec(`INSERT INTO users (name) VALUES ('Alice')`),
+		pg.Exec(`UPDATE users SET email = 'alice@example.com' WHERE name = 'Alice'`),
+		pg.Exec(`DELETE FROM users WHERE name = 'Alice'`),
+	)
+
+	cdc.AssertChanged(t, "users", postgres.Insert, map[string]any{"name": "Alice", "email": nil})
+	cdc.AssertChanged(t, "public.users", postgres.Update, map[string]any{"email": "alice@example.com"})
+	cdc.AssertChanged(t, "users", postgres.Delete, map[string]any{"name": "Alice"})
+
+	var ops []postgres.Op
+	for _, c := range cdc.History() {
+		ops = append(ops, c.Op)
+	}
+	if len(ops) != 3 || ops[0] != postgres.Insert || ops[1] != postgres.Update || ops[2] != postgres.Delete {
+		t.Errorf("History() ops = %v, want [INSERT UPDATE DELETE]", ops)
+	}
+	if old := cdc.History()[1].Old; old["email"] != nil {
+		t.Errorf("update Old = %v, want the row before the update", old)
+	}
+
+	// Losing the slot ends the channel of Changes early, with Err set.
+	changes := cdc.Changes(ctx)
+	testground.Apply(t, pg.Exec(`SELECT pg_drop_replication_slot(slot_name) FROM pg_replication_slots`))
+	for range changes {
+	}
+	if err := cdc.Err(); err == nil {
+		t.Error("Err() = nil after the replication slot was dropped, want error")
+	}
+}
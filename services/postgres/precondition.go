@@ -16,11 +16,34 @@ type execPrecondition struct {
 	args      []pgx.NamedArgs
 }
 
+type reversibleExecPrecondition struct {
+	execPrecondition
+	revertSQL string
+}
+
 func (c *Container) Exec(sql string, args ...pgx.NamedArgs) testground.Precondition {
 	return &execPrecondition{container: c, sql: sql, args: args}
 }
 
+// ExecReversible is like Exec but pairs sql with revertSQL, which undoes its
+// effect. When applied through testground.Apply, revertSQL is executed in
+// t.Cleanup with the same named arguments.
+func (c *Container) ExecReversible(sql, revertSQL string, args ...pgx.NamedArgs) testground.Reverter {
+	return &reversibleExecPrecondition{
+		execPrecondition: execPrecondition{container: c, sql: sql, args: args},
+		revertSQL:        revertSQL,
+	}
+}
+
 func (p *execPrecondition) Apply(ctx context.Context, t *testing.T) error {
+	return p.exec(ctx, p.sql)
+}
+
+func (p *reversibleExecPrecondition) Revert(ctx context.Context, t *testing.T) error {
+	return p.exec(ctx, p.revertSQL)
+}
+
+func (p *execPrecondition) exec(ctx context.Context, sql string) error {
 	pool, err := p.container.Pool(ctx)
 	if err != nil {
 		return fmt.Errorf("connect: %w", err)
@@ -31,6 +54,6 @@ func (p *execPrecondition) Apply(ctx context.Context, t *testing.T) error {
 		namedArgs = p.args[0]
 	}
 
-	_, err = pool.Exec(ctx, p.sql, namedArgs)
+	_, err = pool.Exec(ctx, sql, namedArgs)
 	return err
 }
@@ -114,3 +114,90 @@ func TestExecPrecondition_PoolReuse(t *testing.T) {
 		t.Errorf("TotalConns = %d after 10 sequential preconditions, want <= 2", stat.TotalConns())
 	}
 }
+
+func TestExecReversible_RevertedOnCleanup(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	pg, err := postgres.New(ctx)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { pg.Terminate(context.Background()) })
+
+	testground.Apply(t,
+		pg.Exec(`CREATE TABLE users (id BIGSERIAL PRIMARY KEY, name TEXT NOT NULL)`),
+	)
+
+	count := func() int {
+		t.Helper()
+		pool, err := pg.Pool(ctx)
+		if err != nil {
+			t.Fatalf("Pool() error = %v", err)
+		}
+		var n int
+		if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM users").Scan(&n); err != nil {
+			t.Fatalf("SELECT COUNT(*) error = %v", err)
+		}
+		return n
+	}
+
+	t.Run("inner", func(t *testing.T) {
+		testground.Apply(t,
+			pg.ExecReversible(
+				`INSERT INTO users (name) VALUES (@name)`,
+				`DELETE FROM users WHERE name = @name`,
+				pgx.NamedArgs{"name": "Alice"},
+			),
+		)
+		if got := count(); got != 1 {
+			t.Errorf("count inside test = %d, want 1", got)
+		}
+	})
+
+	if got := count(); got != 0 {
+		t.Errorf("count after cleanup = %d, want 0", got)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	pg, err := postgres.New(ctx)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { pg.Terminate(context.Background()) })
+
+	// Applying twice is a no-op the second time.
+	testground.Apply(t,
+		pg.Migrate("testdata/migrations"),
+		pg.Migrate("testdata/migrations"),
+	)
+
+	pool, err := pg.Pool(ctx)
+	if err != nil {
+		t.Fatalf("Pool() error = %v", err)
+	}
+	if _, err := pool.Exec(ctx, `INSERT INTO users (name, email) VALUES ('Alice', 'alice@example.com')`); err != nil {
+		t.Fatalf("INSERT after Migrate error = %v", err)
+	}
+	var versions int
+	if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM testground_schema_migrations").Scan(&versions); err != nil {
+		t.Fatalf("SELECT applied versions error = %v", err)
+	}
+	if versions != 2 {
+		t.Errorf("applied versions = %d, want 2", versions)
+	}
+
+	testground.Apply(t, pg.MigrateDown("testdata/migrations"))
+
+	var exists bool
+	if err := pool.QueryRow(ctx, "SELECT to_regclass('users') IS NOT NULL").Scan(&exists); err != nil {
+		t.Fatalf("SELECT to_regclass error = %v", err)
+	}
+	if exists {
+		t.Error("table users exists after MigrateDown")
+	}
+}
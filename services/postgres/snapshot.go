@@ -0,0 +1,128 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Snapshot saves the current state of the database under name, replacing an
+// earlier snapshot with the same name. Restore returns the database to it.
+//
+// The snapshot is a copy made with CREATE DATABASE ... TEMPLATE, which takes
+// milliseconds for a test-sized database. Making it terminates the other
+// connections to the database, except those of CaptureQueries and
+// CaptureChanges. The pool returned by Pool stays usable: it discards the
+// terminated connections and opens new ones, and a connection acquired from
+// it fails and is discarded on release.
+func (c *Container) Snapshot(ctx context.Context, name string) error {
+	snapshot, err := snapshotName(name)
+	if err != nil {
+		return err
+	}
+	conn, err := c.maintenanceConn(ctx)
+	if err != nil {
+		return fmt.Errorf("snapshot: connect: %w", err)
+	}
+	defer conn.Close(ctx)
+
+	if err := c.copyDatabase(ctx, conn, c.cfg.database, snapshot); err != nil {
+		return fmt.Errorf("snapshot %q: %w", name, err)
+	}
+	return nil
+}
+
+// Restore returns the database to the state saved by Snapshot under name.
+// Open connections to the database are terminated; the pool returned by Pool
+// stays usable as with Snapshot, and a service under test must reconnect.
+// The snapshot is kept, so Restore can be called before every test. It fails
+// while a CaptureChanges stream is open, whose slot belongs to the database.
+func (c *Container) Restore(ctx context.Context, name string) error {
+	snapshot, err := snapshotName(name)
+	if err != nil {
+		return err
+	}
+	conn, err := c.maintenanceConn(ctx)
+	if err != nil {
+		return fmt.Errorf("restore: connect: %w", err)
+	}
+	defer conn.Close(ctx)
+
+	var exists bool
+	if err := conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)", snapshot).Scan(&exists); err != nil {
+		return fmt.Errorf("restore %q: %w", name, err)
+	}
+	if !exists {
+		return fmt.Errorf("restore %q: no such snapshot", name)
+	}
+
+	if err := dropDatabase(ctx, conn, c.cfg.database); err != nil {
+		return fmt.Errorf("restore %q: drop database: %w", name, err)
+	}
+	db := pgx.Identifier{c.cfg.database}.Sanitize()
+	if _, err := conn.Exec(ctx, fmt.Sprintf("CREATE DATABASE %s TEMPLATE %s", db, pgx.Identifier{snapshot}.Sanitize())); err != nil {
+		return fmt.Errorf("restore %q: create database: %w", name, err)
+	}
+	c.resetPool()
+	return nil
+}
+
+// snapshotName returns the name of the database that holds snapshot name.
+func snapshotName(name string) (string, error) {
+	db := "testground_snapshot_" + name
+	// PostgreSQL truncates longer identifiers, which could make two
+	// snapshots collide.
+	if name == "" || len(db) > 63 {
+		return "", fmt.Errorf("postgres: invalid snapshot name %q: must be 1 to %d bytes", name, 63-len("testground_snapshot_"))
+	}
+	return db, nil
+}
+
+// copyDatabase replaces the database dst with a copy of src and forbids
+// connections to dst, so it can itself be copied at any time.
+func (c *Container) copyDatabase(ctx context.Context, conn *pgx.Conn, src, dst string) error {
+	target := pgx.Identifier{dst}.Sanitize()
+	if _, err := conn.Exec(ctx, "DROP DATABASE IF EXISTS "+target); err != nil {
+		return fmt.Errorf("drop %s: %w", dst, err)
+	}
+
+	// The source of CREATE DATABASE must not have other sessions. A service
+	// under test may reconnect right after its sessions are terminated, so
+	// try a few times.
+	var err error
+	for attempt := range 5 {
+		if attempt > 0 {
+			time.Sleep(100 * time.Millisecond)
+		}
+		if err = terminateSessions(ctx, conn, src); err != nil {
+			return fmt.Errorf("terminate connections: %w", err)
+		}
+		_, err = conn.Exec(ctx, fmt.Sprintf("CREATE DATABASE %s TEMPLATE %s", target, pgx.Identifier{src}.Sanitize()))
+		if err == nil {
+			break
+		}
+	}
+	if src == c.cfg.database {
+		c.resetPool()
+	}
+	if err != nil {
+		return fmt.Errorf("copy %s: %w", src, err)
+	}
+
+	if _, err := conn.Exec(ctx, fmt.Sprintf("ALTER DATABASE %s ALLOW_CONNECTIONS false", target)); err != nil {
+		return fmt.Errorf("copy %s: %w", src, err)
+	}
+	return nil
+}
+
+// terminateSessions terminates the other sessions connected to database,
+// except those of CaptureQueries and CaptureChanges. These only connect to it
+// for a moment, so waiting for them keeps the capture working.
+func terminateSessions(ctx context.Context, conn *pgx.Conn, database string) error {
+	_, err := conn.Exec(ctx,
+		"SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname = $1 AND pid <> pg_backend_pid() AND application_name <> ALL($2)",
+		database, []string{captureApp, cdcApp})
+	return err
+}
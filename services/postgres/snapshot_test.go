@@ -0,0 +1,145 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dsvdev/testground"
+	"github.com/dsvdev/testground/services/postgres"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	pg, err := postgres.New(ctx)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { pg.Terminate(context.Background()) })
+
+	testground.Apply(t,
+		pg.Migrate("testdata/migrations"),
+		pg.Exec(`INSERT INTO users (name) VALUES ('Alice')`),
+	)
+	if err := pg.Snapshot(ctx, "seeded"); err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+
+	count := func() int {
+		t.Helper()
+		pool, err := pg.Pool(ctx)
+		if err != nil {
+			t.Fatalf("Pool() error = %v", err)
+		}
+		var n int
+		if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM users").Scan(&n); err != nil {
+			t.Fatalf("SELECT COUNT(*) error = %v", err)
+		}
+		return n
+	}
+
+	// Restoring twice checks that the snapshot survives a restore.
+	for range 2 {
+		testground.Apply(t, pg.Exec(`INSERT INTO users (name) VALUES ('Bob')`))
+		if got := count(); got != 2 {
+			t.Fatalf("count before Restore = %d, want 2", got)
+		}
+		if err := pg.Restore(ctx, "seeded"); err != nil {
+			t.Fatalf("Restore() error = %v", err)
+		}
+		if got := count(); got != 1 {
+			t.Errorf("count after Restore = %d, want 1", got)
+		}
+	}
+
+	if err := pg.Restore(ctx, "missing"); err == nil {
+		t.Error("Restore() of a missing snapshot returned no error")
+	}
+}
+
+func TestRestore_KeepsPoolAndCaptures(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	pg, err := postgres.New(ctx)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { pg.Terminate(context.Background()) })
+
+	testground.Apply(t, pg.Migrate("testdata/migrations"))
+	if err := pg.Snapshot(ctx, "empty"); err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+
+	// As in a suite: the pool is taken once and held across restores.
+	pool, err := pg.Pool(ctx)
+	if err != nil {
+		t.Fatalf("Pool() error = %v", err)
+	}
+	held, err := pool.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	rec := pg.CaptureQueries(t)
+
+	if err := pg.Restore(ctx, "empty"); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	held.Release()
+
+	if again, err := pg.Pool(ctx); err != nil || again != pool {
+		t.Errorf("Pool() after Restore = %p, %v; want the same pool %p", again, err, pool)
+	}
+	var n int
+	if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM users").Scan(&n); err != nil {
+		t.Fatalf("query through the pool after Restore: %v", err)
+	}
+	rec.AssertQueryCount(t, `SELECT COUNT\(\*\) FROM users`, 1)
+}
+
+// TestRestore_PostgreSQL12 covers the drop without WITH (FORCE), which
+// PostgreSQL 12 lacks, while the pool holds connections to the database.
+func TestRestore_PostgreSQL12(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 90*time.Second)
+	defer cancel()
+
+	pg, err := postgres.New(ctx, postgres.WithVersion("12"))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { pg.Terminate(context.Background()) })
+
+	testground.Apply(t,
+		pg.Migrate("testdata/migrations"),
+		pg.Exec(`INSERT INTO users (name) VALUES ('Alice')`),
+	)
+	if err := pg.Snapshot(ctx, "seeded"); err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	testground.Apply(t, pg.Exec(`INSERT INTO users (name) VALUES ('Bob')`))
+
+	pool, err := pg.Pool(ctx)
+	if err != nil {
+		t.Fatalf("Pool() error = %v", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+	if err := pg.Restore(ctx, "seeded"); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	var n int
+	if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM users").Scan(&n); err != nil {
+		t.Fatalf("SELECT COUNT(*) error = %v", err)
+	}
+	if n != 1 {
+		t.Errorf("count after Restore = %d, want 1", n)
+	}
+
+	if err := pg.Reset(ctx); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+}
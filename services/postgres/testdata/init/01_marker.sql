@@ -0,0 +1,2 @@
+CREATE TABLE init_marker (id INT);
+INSERT INTO init_marker VALUES (1);
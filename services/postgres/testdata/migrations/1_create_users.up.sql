@@ -0,0 +1,4 @@
+CREATE TABLE users (
+    id   BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL
+);
@@ -0,0 +1,42 @@
+package toxiproxy
+
+import "github.com/dsvdev/testground"
+
+type config struct {
+	version      string
+	networkName  string
+	networkAlias string
+}
+
+func defaultConfig() config {
+	return config{
+		version:      "2.9.0",
+		networkAlias: "toxiproxy",
+	}
+}
+
+type Option func(*config)
+
+// WithVersion sets the ghcr.io/shopify/toxiproxy image version.
+// Default: "2.9.0".
+func WithVersion(v string) Option {
+	return func(c *config) {
+		c.version = v
+	}
+}
+
+// WithNetwork attaches Toxiproxy to a Docker network so that other containers
+// can reach the proxies via Proxy.NetworkAddress.
+func WithNetwork(n *testground.Network) Option {
+	return func(c *config) {
+		c.networkName = n.Name()
+	}
+}
+
+// WithNetworkAlias sets the alias for Toxiproxy inside the network.
+// Default: "toxiproxy".
+func WithNetworkAlias(alias string) Option {
+	return func(c *config) {
+		c.networkAlias = alias
+	}
+}
@@ -0,0 +1,121 @@
+package toxiproxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// Stream selects the direction of traffic a toxic applies to.
+type Stream string
+
+const (
+	// Downstream is traffic from the upstream server back to the client.
+	Downstream Stream = "downstream"
+	// Upstream is traffic from the client to the upstream server.
+	Upstream Stream = "upstream"
+)
+
+// Toxic describes a raw Toxiproxy toxic. The helpers on Proxy cover the common
+// types; AddToxic accepts any toxic the Toxiproxy API knows about.
+type Toxic struct {
+	// Type is the toxic type, e.g. "latency", "bandwidth" or "slicer".
+	Type string
+	// Stream defaults to Downstream.
+	Stream Stream
+	// Toxicity is the probability the toxic applies to a connection, from 0
+	// to 1. Zero means 1.
+	Toxicity float64
+	// Attributes are the type-specific settings, see the Toxiproxy docs.
+	Attributes map[string]any
+}
+
+// AddToxic adds toxic to the proxy for the rest of the test. It is removed
+// in t.Cleanup, so the next test starts with a clean proxy.
+func (p *Proxy) AddToxic(t *testing.T, toxic Toxic) {
+	t.Helper()
+
+	if toxic.Stream == "" {
+		toxic.Stream = Downstream
+	}
+	if toxic.Toxicity == 0 {
+		toxic.Toxicity = 1
+	}
+
+	p.mu.Lock()
+	p.toxics++
+	name := fmt.Sprintf("%s_%s_%d", toxic.Type, toxic.Stream, p.toxics)
+	p.mu.Unlock()
+
+	body := map[string]any{
+		"name":       name,
+		"type":       toxic.Type,
+		"stream":     toxic.Stream,
+		"toxicity":   toxic.Toxicity,
+		"attributes": toxic.Attributes,
+	}
+	if err := p.c.do(context.Background(), http.MethodPost, "/proxies/"+p.name+"/toxics", body); err != nil {
+		t.Fatalf("toxiproxy: add %s toxic to %q: %v", toxic.Type, p.name, err)
+	}
+
+	t.Cleanup(func() {
+		if err := p.c.do(context.Background(), http.MethodDelete, "/proxies/"+p.name+"/toxics/"+name, nil); err != nil {
+			t.Logf("warning: failed to remove %s toxic from %q: %v", toxic.Type, p.name, err)
+		}
+	})
+}
+
+// AddLatency delays all data flowing back to the client by latency ± jitter.
+func (p *Proxy) AddLatency(t *testing.T, latency, jitter time.Duration) {
+	t.Helper()
+	p.AddToxic(t, Toxic{
+		Type: "latency",
+		Attributes: map[string]any{
+			"latency": latency.Milliseconds(),
+			"jitter":  jitter.Milliseconds(),
+		},
+	})
+}
+
+// AddBandwidth limits traffic in both directions to rate KB/s.
+func (p *Proxy) AddBandwidth(t *testing.T, rate int) {
+	t.Helper()
+	for _, s := range []Stream{Downstream, Upstream} {
+		p.AddToxic(t, Toxic{Type: "bandwidth", Stream: s, Attributes: map[string]any{"rate": rate}})
+	}
+}
+
+// AddResetPeer resets every connection with a TCP RST after timeout.
+// A zero timeout resets connections as soon as data arrives.
+func (p *Proxy) AddResetPeer(t *testing.T, timeout time.Duration) {
+	t.Helper()
+	p.AddToxic(t, Toxic{Type: "reset_peer", Attributes: map[string]any{"timeout": timeout.Milliseconds()}})
+}
+
+// AddTimeout stops all data from getting through and closes the connection
+// after timeout. A zero timeout keeps connections open but silent until the
+// toxic is removed, which looks like a black-holed network to the client.
+func (p *Proxy) AddTimeout(t *testing.T, timeout time.Duration) {
+	t.Helper()
+	p.AddToxic(t, Toxic{Type: "timeout", Attributes: map[string]any{"timeout": timeout.Milliseconds()}})
+}
+
+// Partition disables the proxy for the rest of the test: open connections are
+// dropped and new ones are refused. The proxy is re-enabled in t.Cleanup.
+func (p *Proxy) Partition(t *testing.T) {
+	t.Helper()
+	if err := p.setEnabled(false); err != nil {
+		t.Fatalf("toxiproxy: partition %q: %v", p.name, err)
+	}
+	t.Cleanup(func() {
+		if err := p.setEnabled(true); err != nil {
+			t.Logf("warning: failed to re-enable proxy %q: %v", p.name, err)
+		}
+	})
+}
+
+func (p *Proxy) setEnabled(enabled bool) error {
+	return p.c.do(context.Background(), http.MethodPost, "/proxies/"+p.name, map[string]any{"enabled": enabled})
+}
@@ -0,0 +1,272 @@
+// Package toxiproxy runs a Toxiproxy container that sits between a service and
+// its dependencies and injects network faults into the traffic: latency,
+// bandwidth limits, connection resets and partitions.
+//
+// Point the service at Proxy.NetworkAddress instead of the dependency's own
+// address, then add toxics from inside a test. Every toxic is scoped to the
+// test that added it and removed in t.Cleanup.
+package toxiproxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/dsvdev/testground"
+	"github.com/dsvdev/testground/internal/container"
+)
+
+const (
+	apiPort = "8474"
+	// firstProxyPort and maxProxies define the range of container ports
+	// exposed for proxies. Ports must be published when the container is
+	// created, so the range is fixed.
+	firstProxyPort = 8666
+	maxProxies     = 16
+)
+
+type Container struct {
+	base   *container.Base
+	cfg    config
+	client *http.Client
+
+	mu      sync.Mutex
+	proxies map[string]*Proxy
+}
+
+func New(ctx context.Context, opts ...Option) (*Container, error) {
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	// Bind explicit host ports: Docker keeps explicit bindings across Stop
+	// and Start, so Proxy.Address stays valid after a restart.
+	var exposed []string
+	for _, port := range append([]string{apiPort}, proxyPorts()...) {
+		hostPort, err := container.FreePort()
+		if err != nil {
+			return nil, fmt.Errorf("toxiproxy: find free port: %w", err)
+		}
+		exposed = append(exposed, fmt.Sprintf("%d:%s/tcp", hostPort, port))
+	}
+
+	req := testcontainers.ContainerRequest{
+		Image:        fmt.Sprintf("ghcr.io/shopify/toxiproxy:%s", cfg.version),
+		ExposedPorts: exposed,
+		WaitingFor:   wait.ForHTTP("/version").WithPort(apiPort + "/tcp"),
+	}
+
+	if cfg.networkName != "" {
+		req.Networks = []string{cfg.networkName}
+		req.NetworkAliases = map[string][]string{
+			cfg.networkName: {cfg.networkAlias},
+		}
+	}
+
+	base, err := container.Start(ctx, req, apiPort)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Container{
+		base:    base,
+		cfg:     cfg,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		proxies: make(map[string]*Proxy),
+	}, nil
+}
+
+// proxyPorts returns the container ports exposed for proxies.
+func proxyPorts() []string {
+	ports := make([]string, maxProxies)
+	for i := range ports {
+		ports[i] = strconv.Itoa(firstProxyPort + i)
+	}
+	return ports
+}
+
+// Proxy creates a proxy named name that forwards to upstream, a host:port
+// reachable from the Toxiproxy container, e.g. "postgres:5432" on a shared
+// network. Up to 16 proxies can be created per container.
+func (c *Container) Proxy(ctx context.Context, name, upstream string) (*Proxy, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.proxies[name]; ok {
+		return nil, fmt.Errorf("toxiproxy: proxy %q already exists", name)
+	}
+	if len(c.proxies) == maxProxies {
+		return nil, fmt.Errorf("toxiproxy: at most %d proxies are supported", maxProxies)
+	}
+	port := firstProxyPort + len(c.proxies)
+
+	hostPort, err := c.base.MappedPort(ctx, nat.Port(fmt.Sprintf("%d/tcp", port)))
+	if err != nil {
+		return nil, fmt.Errorf("toxiproxy: %w", err)
+	}
+
+	p := &Proxy{c: c, name: name, upstream: upstream, port: port, hostPort: hostPort}
+	if err := c.create(ctx, p); err != nil {
+		return nil, err
+	}
+	c.proxies[name] = p
+	return p, nil
+}
+
+// create creates p in the Toxiproxy API.
+func (c *Container) create(ctx context.Context, p *Proxy) error {
+	body := map[string]any{
+		"name":     p.name,
+		"listen":   fmt.Sprintf("0.0.0.0:%d", p.port),
+		"upstream": p.upstream,
+		"enabled":  true,
+	}
+	if err := c.do(ctx, http.MethodPost, "/proxies", body); err != nil {
+		return fmt.Errorf("toxiproxy: create proxy %q: %w", p.name, err)
+	}
+	return nil
+}
+
+// Stop stops the Toxiproxy container without removing it. Connections
+// through every proxy fail until Start.
+func (c *Container) Stop(ctx context.Context) error {
+	return c.base.Stop(ctx, nil)
+}
+
+// Start starts a container stopped with Stop, waits until the API responds
+// and creates the proxies again, on the same addresses. Toxiproxy keeps its
+// state in memory only, so toxics added before Stop are gone and proxies
+// disabled by Partition are enabled again.
+func (c *Container) Start(ctx context.Context) error {
+	if err := c.base.Start(ctx); err != nil {
+		return err
+	}
+	if err := testground.WaitHealthy(ctx, c); err != nil {
+		return fmt.Errorf("wait for toxiproxy: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	proxies := slices.SortedFunc(maps.Values(c.proxies), func(a, b *Proxy) int { return a.port - b.port })
+	for _, p := range proxies {
+		if err := c.create(ctx, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Restart stops and starts the container, see Stop and Start.
+func (c *Container) Restart(ctx context.Context) error {
+	if err := c.Stop(ctx); err != nil {
+		return err
+	}
+	return c.Start(ctx)
+}
+
+// Pause freezes Toxiproxy until Unpause: connections through every proxy
+// hang instead of failing.
+func (c *Container) Pause(ctx context.Context) error {
+	return c.base.Pause(ctx)
+}
+
+// Unpause resumes a container frozen with Pause.
+func (c *Container) Unpause(ctx context.Context) error {
+	return c.base.Unpause(ctx)
+}
+
+// Healthy reports whether the Toxiproxy API responds.
+func (c *Container) Healthy(ctx context.Context) error {
+	return c.do(ctx, http.MethodGet, "/version", nil)
+}
+
+// ContainerID returns the Docker ID of the Toxiproxy container.
+func (c *Container) ContainerID() string {
+	return c.base.ID()
+}
+
+// Logs returns the Toxiproxy output produced so far. The caller must close
+// the returned reader.
+func (c *Container) Logs(ctx context.Context) (io.ReadCloser, error) {
+	return c.base.Logs(ctx)
+}
+
+// LogsSince returns the Toxiproxy output produced since the Docker engine
+// time since. The caller must close the returned reader.
+func (c *Container) LogsSince(ctx context.Context, since time.Time) (io.ReadCloser, error) {
+	return c.base.LogsSince(ctx, since)
+}
+
+func (c *Container) Terminate(ctx context.Context) error {
+	return c.base.Terminate(ctx)
+}
+
+// do sends a request to the Toxiproxy HTTP API and fails on any non-2xx status.
+func (c *Container) do(ctx context.Context, method, path string, body any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+
+	url := "http://" + c.base.Host() + ":" + c.base.Port() + path
+	req, err := http.NewRequestWithContext(ctx, method, url, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s %s: status %d: %s", method, path, resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// Proxy is a single TCP proxy inside the Toxiproxy container.
+type Proxy struct {
+	c        *Container
+	name     string
+	upstream string
+	port     int
+	hostPort string
+
+	mu     sync.Mutex
+	toxics int
+}
+
+// Name returns the proxy name.
+func (p *Proxy) Name() string { return p.name }
+
+// Address returns the host:port of the proxy as seen from the test process.
+func (p *Proxy) Address() string {
+	return p.c.base.Host() + ":" + p.hostPort
+}
+
+// NetworkAddress returns the host:port of the proxy as seen from other
+// containers on the network Toxiproxy was attached to with WithNetwork.
+func (p *Proxy) NetworkAddress() string {
+	return p.c.cfg.networkAlias + ":" + strconv.Itoa(p.port)
+}
@@ -0,0 +1,171 @@
+package toxiproxy_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/dsvdev/testground"
+	"github.com/dsvdev/testground/services/postgres"
+	"github.com/dsvdev/testground/services/toxiproxy"
+)
+
+// setup starts PostgreSQL behind a Toxiproxy proxy on a shared network.
+func setup(t *testing.T) (*testground.Network, *postgres.Container, *toxiproxy.Container, *toxiproxy.Proxy) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	defer cancel()
+
+	net, err := testground.NewNetwork(ctx)
+	if err != nil {
+		t.Fatalf("NewNetwork() error = %v", err)
+	}
+	t.Cleanup(func() { net.Terminate(context.Background()) })
+
+	pg, err := postgres.New(ctx, postgres.WithNetwork(net), postgres.WithNetworkAlias("postgres"))
+	if err != nil {
+		t.Fatalf("postgres.New() error = %v", err)
+	}
+	t.Cleanup(func() { pg.Terminate(context.Background()) })
+
+	tp, err := toxiproxy.New(ctx, toxiproxy.WithNetwork(net))
+	if err != nil {
+		t.Fatalf("toxiproxy.New() error = %v", err)
+	}
+	t.Cleanup(func() { tp.Terminate(context.Background()) })
+
+	proxy, err := tp.Proxy(ctx, "postgres", "postgres:5432")
+	if err != nil {
+		t.Fatalf("Proxy() error = %v", err)
+	}
+	return net, pg, tp, proxy
+}
+
+// ping connects to PostgreSQL through the proxy and runs SELECT 1.
+func ping(ctx context.Context, proxy *toxiproxy.Proxy) error {
+	conn, err := pgx.Connect(ctx, fmt.Sprintf("postgres://test:test@%s/test?sslmode=disable", proxy.Address()))
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+	var one int
+	return conn.QueryRow(ctx, "SELECT 1").Scan(&one)
+}
+
+func TestToxiproxy_Toxics(t *testing.T) {
+	_, _, _, proxy := setup(t)
+
+	if err := ping(context.Background(), proxy); err != nil {
+		t.Fatalf("ping through proxy error = %v", err)
+	}
+
+	t.Run("latency", func(t *testing.T) {
+		proxy.AddLatency(t, 300*time.Millisecond, 0)
+
+		start := time.Now()
+		if err := ping(context.Background(), proxy); err != nil {
+			t.Fatalf("ping error = %v", err)
+		}
+		if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+			t.Errorf("ping took %v, want at least 300ms", elapsed)
+		}
+	})
+
+	t.Run("partition", func(t *testing.T) {
+		proxy.Partition(t)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if err := ping(ctx, proxy); err == nil {
+			t.Error("ping succeeded through a partitioned proxy")
+		}
+	})
+
+	// Toxics of the subtests are removed by their cleanups.
+	start := time.Now()
+	if err := ping(context.Background(), proxy); err != nil {
+		t.Fatalf("ping after subtests error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 300*time.Millisecond {
+		t.Errorf("ping took %v after subtests, latency toxic was not removed", elapsed)
+	}
+}
+
+func TestToxiproxy_Restart(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	_, _, tp, proxy := setup(t)
+
+	if err := tp.Stop(ctx); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if err := ping(ctx, proxy); err == nil {
+		t.Error("ping after Stop() succeeded, want error")
+	}
+	if err := tp.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := ping(ctx, proxy); err != nil {
+		t.Errorf("ping after Start() error = %v, want the proxy recreated on the same address", err)
+	}
+
+	if err := tp.Pause(ctx); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+	pingCtx, pingCancel := context.WithTimeout(ctx, time.Second)
+	err := ping(pingCtx, proxy)
+	pingCancel()
+	if err == nil {
+		t.Error("ping while paused succeeded, want timeout")
+	}
+	if err := tp.Unpause(ctx); err != nil {
+		t.Fatalf("Unpause() error = %v", err)
+	}
+	if err := ping(ctx, proxy); err != nil {
+		t.Errorf("ping after Unpause() error = %v", err)
+	}
+}
+
+func TestNetwork_DisconnectReconnect(t *testing.T) {
+	net, pg, _, proxy := setup(t)
+	ctx := context.Background()
+
+	if err := net.Disconnect(ctx, pg); err != nil {
+		t.Fatalf("Disconnect() error = %v", err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	if err := ping(timeoutCtx, proxy); err == nil {
+		t.Error("postgres reachable over the network after Disconnect")
+	}
+
+	if err := net.Reconnect(ctx, pg); err != nil {
+		t.Fatalf("Reconnect() error = %v", err)
+	}
+	if err := ping(ctx, proxy); err != nil {
+		t.Errorf("postgres unreachable after Reconnect: %v", err)
+	}
+}
+
+func TestNetwork_Partition(t *testing.T) {
+	net, pg, _, proxy := setup(t)
+	ctx := context.Background()
+
+	t.Run("partitioned", func(t *testing.T) {
+		net.Partition(t, pg)
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+		defer cancel()
+		if err := ping(timeoutCtx, proxy); err == nil {
+			t.Error("postgres reachable over the network after Partition")
+		}
+	})
+
+	if err := ping(ctx, proxy); err != nil {
+		t.Errorf("postgres unreachable after the partitioned test ended: %v", err)
+	}
+}
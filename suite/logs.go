@@ -0,0 +1,83 @@
+package suite
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// LogSource is implemented by containers that can return their output.
+// Every container type in testground implements it.
+type LogSource interface {
+	Logs(ctx context.Context) (io.ReadCloser, error)
+}
+
+// SinceLogSource is implemented by containers that can return only the
+// output produced since a point in time, without reading the earlier output.
+// Every container type in testground implements it.
+type SinceLogSource interface {
+	LogsSince(ctx context.Context, since time.Time) (io.ReadCloser, error)
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// dumpLogs collects the logs of every container that implements LogSource.
+// Logs are passed to logf, or written to files in cfg.logsDir named after
+// prefix when a directory is configured. With a non-zero since, containers
+// that implement SinceLogSource dump only the output produced since then.
+func dumpLogs(cfg config, containers []Managed, since time.Time, prefix string, logf func(format string, args ...any)) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if cfg.logsDir != "" {
+		if err := os.MkdirAll(cfg.logsDir, 0o755); err != nil {
+			logf("warning: failed to create logs directory: %v", err)
+			return
+		}
+	}
+
+	for i, c := range containers {
+		var open func(ctx context.Context) (io.ReadCloser, error)
+		if src, ok := c.(SinceLogSource); ok && !since.IsZero() {
+			open = func(ctx context.Context) (io.ReadCloser, error) { return src.LogsSince(ctx, since) }
+		} else if src, ok := c.(LogSource); ok {
+			open = src.Logs
+		} else {
+			continue
+		}
+		name := fmt.Sprintf("%d-%T", i, c)
+
+		logs, err := readLogs(ctx, open)
+		if err != nil {
+			logf("warning: failed to read logs of %s: %v", name, err)
+			continue
+		}
+
+		if cfg.logsDir == "" {
+			logf("logs of %s:\n%s", name, logs)
+			continue
+		}
+
+		file := unsafeFileChars.ReplaceAllString(prefix+"-"+name, "_") + ".log"
+		path := filepath.Join(cfg.logsDir, file)
+		if err := os.WriteFile(path, logs, 0o644); err != nil {
+			logf("warning: failed to write logs of %s: %v", name, err)
+			continue
+		}
+		logf("logs of %s written to %s", name, path)
+	}
+}
+
+// readLogs reads the output returned by open.
+func readLogs(ctx context.Context, open func(ctx context.Context) (io.ReadCloser, error)) ([]byte, error) {
+	rc, err := open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
@@ -0,0 +1,53 @@
+package suite
+
+import "context"
+
+type config struct {
+	logsOnFailure bool
+	logsDir       string
+	restores      []restore
+}
+
+// Restorer is a container that can return to a named snapshot, such as
+// postgres.Container.
+type Restorer interface {
+	Restore(ctx context.Context, name string) error
+}
+
+type restore struct {
+	r        Restorer
+	snapshot string
+}
+
+type Option func(*config)
+
+// WithLogsOnFailure attaches the output of every registered container that
+// has a Logs method to the test output via t.Log when a test fails. A failed
+// Run subtest gets only the output produced while it ran, if the container
+// implements SinceLogSource. For a MainSuite the
+// logs are printed to stdout when the test binary fails.
+func WithLogsOnFailure() Option {
+	return func(c *config) {
+		c.logsOnFailure = true
+	}
+}
+
+// WithLogsDir writes the container logs of failed tests to files in dir
+// instead of the test output. The directory is created if needed. Implies
+// WithLogsOnFailure.
+func WithLogsDir(dir string) Option {
+	return func(c *config) {
+		c.logsOnFailure = true
+		c.logsDir = dir
+	}
+}
+
+// WithRestore restores r to snapshot before every Run, after the BeforeAll
+// hooks and before the BeforeEach hooks, so each test starts from the same
+// state. Take the snapshot in TestMain or a BeforeAll hook. It can be given
+// more than once. A failed restore fails the test. MainSuite ignores it.
+func WithRestore(r Restorer, snapshot string) Option {
+	return func(c *config) {
+		c.restores = append(c.restores, restore{r: r, snapshot: snapshot})
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"sync"
 	"testing"
+	"time"
+
+	"github.com/dsvdev/testground/internal/session"
 )
 
 type Managed interface {
@@ -13,12 +16,17 @@ type Managed interface {
 
 type MainSuite struct {
 	m          *testing.M
+	cfg        config
 	mu         sync.Mutex
 	containers []Managed
 }
 
-func NewMain(m *testing.M) *MainSuite {
-	return &MainSuite{m: m}
+func NewMain(m *testing.M, opts ...Option) *MainSuite {
+	s := &MainSuite{m: m}
+	for _, opt := range opts {
+		opt(&s.cfg)
+	}
+	return s
 }
 
 func (s *MainSuite) Add(c Managed) {
@@ -42,14 +50,27 @@ func (s *MainSuite) Cleanup() {
 
 func (s *MainSuite) Run() int {
 	code := s.m.Run()
+	if code != 0 && s.cfg.logsOnFailure {
+		s.mu.Lock()
+		containers := s.containers
+		s.mu.Unlock()
+		dumpLogs(s.cfg, containers, time.Time{}, "main", func(format string, args ...any) {
+			fmt.Printf(format+"\n", args...)
+		})
+	}
 	s.Cleanup()
+	// The process exits next, so nothing is left for Reap to judge by the
+	// heartbeat.
+	session.StopHeartbeat()
 	return code
 }
 
 type Suite struct {
 	t          *testing.T
+	cfg        config
 	mu         sync.Mutex
 	containers []Managed
+	logsDumped bool
 
 	beforeAll  []func(ctx context.Context)
 	afterAll   []func(ctx context.Context)
@@ -60,8 +81,11 @@ type Suite struct {
 	started    bool
 }
 
-func New(t *testing.T) *Suite {
+func New(t *testing.T, opts ...Option) *Suite {
 	s := &Suite{t: t}
+	for _, opt := range opts {
+		opt(&s.cfg)
+	}
 
 	t.Cleanup(func() {
 		ctx := context.Background()
@@ -71,11 +95,17 @@ func New(t *testing.T) *Suite {
 			hook(ctx)
 		}
 
-		// Terminate all containers in reverse order
 		s.mu.Lock()
 		containers := s.containers
+		dumped := s.logsDumped
 		s.mu.Unlock()
 
+		// Dump logs unless a failed subtest has already done so
+		if s.cfg.logsOnFailure && t.Failed() && !dumped {
+			dumpLogs(s.cfg, containers, time.Time{}, t.Name(), t.Logf)
+		}
+
+		// Terminate all containers in reverse order
 		for i := len(containers) - 1; i >= 0; i-- {
 			if err := containers[i].Terminate(ctx); err != nil {
 				s.t.Logf("warning: failed to terminate container: %v", err)
@@ -135,6 +165,9 @@ func (s *Suite) Run(name string, fn func(t *testing.T)) {
 	s.t.Run(name, func(t *testing.T) {
 		ctx := context.Background()
 
+		// A failure dumps only the output produced from now on
+		started := time.Now()
+
 		// Call BeforeAll once before the first test
 		s.beforeOnce.Do(func() {
 			for _, hook := range s.beforeAll {
@@ -142,6 +175,12 @@ func (s *Suite) Run(name string, fn func(t *testing.T)) {
 			}
 		})
 
+		for _, r := range s.cfg.restores {
+			if err := r.r.Restore(ctx, r.snapshot); err != nil {
+				t.Fatalf("suite: restore snapshot %q: %v", r.snapshot, err)
+			}
+		}
+
 		// Call BeforeEach hooks
 		for _, hook := range s.beforeEach {
 			hook(ctx)
@@ -154,6 +193,20 @@ func (s *Suite) Run(name string, fn func(t *testing.T)) {
 			}
 		})
 
+		// Registered last so it runs first, before AfterEach can change state
+		if s.cfg.logsOnFailure {
+			t.Cleanup(func() {
+				if !t.Failed() {
+					return
+				}
+				s.mu.Lock()
+				containers := s.containers
+				s.logsDumped = true
+				s.mu.Unlock()
+				dumpLogs(s.cfg, containers, started, t.Name(), t.Logf)
+			})
+		}
+
 		fn(t)
 	})
 }
@@ -2,6 +2,11 @@ package suite_test
 
 import (
 	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -108,6 +113,39 @@ func TestSuite_HooksCalledInOrder(t *testing.T) {
 	}
 }
 
+// recordingRestorer records the snapshots it is restored to.
+type recordingRestorer struct {
+	record func(event string)
+}
+
+func (r recordingRestorer) Restore(ctx context.Context, name string) error {
+	r.record("Restore " + name)
+	return nil
+}
+
+func TestSuite_WithRestore(t *testing.T) {
+	var order []string
+	record := func(event string) { order = append(order, event) }
+
+	t.Run("suite", func(t *testing.T) {
+		s := suite.New(t, suite.WithRestore(recordingRestorer{record}, "seeded"))
+		s.BeforeAll(func(ctx context.Context) { record("BeforeAll") })
+		s.BeforeEach(func(ctx context.Context) { record("BeforeEach") })
+
+		s.Run("test1", func(t *testing.T) { record("test1") })
+		s.Run("test2", func(t *testing.T) { record("test2") })
+	})
+
+	expected := []string{
+		"BeforeAll",
+		"Restore seeded", "BeforeEach", "test1",
+		"Restore seeded", "BeforeEach", "test2",
+	}
+	if strings.Join(order, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("events = %v, want %v", order, expected)
+	}
+}
+
 func mustPanic(t *testing.T, fn func()) {
 	t.Helper()
 	defer func() {
@@ -206,3 +244,143 @@ func TestSuite_IsolatedContainers(t *testing.T) {
 	t.Logf("Container A: %s", port1)
 	t.Logf("Container B: %s", port2)
 }
+
+// logsManaged is a mock container that also implements suite.LogSource
+type logsManaged struct {
+	mockManaged
+	logs string
+}
+
+func (m *logsManaged) Logs(ctx context.Context) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader(m.logs)), nil
+}
+
+func TestSuite_LogsNotDumpedOnSuccess(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("inner", func(t *testing.T) {
+		s := suite.New(t, suite.WithLogsDir(dir))
+		s.Add(&logsManaged{logs: "hello from container"})
+		s.Run("passes", func(t *testing.T) {})
+	})
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("logs written for a passing suite: %v", entries)
+	}
+}
+
+// TestSuite_LogsDumpedOnFailure re-runs itself in a subprocess, because the
+// scenario under test requires a failing test.
+func TestSuite_LogsDumpedOnFailure(t *testing.T) {
+	if dir := os.Getenv("SUITE_LOGS_DIR"); dir != "" {
+		s := suite.New(t, suite.WithLogsDir(dir))
+		c := &growingLogs{}
+		s.Add(c)
+		s.Run("fails", func(t *testing.T) {
+			c.write("hello from container")
+			t.Error("intentional failure")
+		})
+		return
+	}
+
+	dir := t.TempDir()
+	cmd := exec.Command(os.Args[0], "-test.run=^TestSuite_LogsDumpedOnFailure$")
+	cmd.Env = append(os.Environ(), "SUITE_LOGS_DIR="+dir)
+	if out, err := cmd.CombinedOutput(); err == nil {
+		t.Fatalf("helper test passed, expected failure:\n%s", out)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d log files, want 1: %v", len(entries), entries)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if string(data) != "hello from container" {
+		t.Errorf("log file = %q, want %q", data, "hello from container")
+	}
+}
+
+// growingLogs is a container whose log grows with every write.
+type growingLogs struct {
+	mockManaged
+	mu      sync.Mutex
+	entries []logEntry
+}
+
+type logEntry struct {
+	time time.Time
+	text string
+}
+
+func (g *growingLogs) write(s string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.entries = append(g.entries, logEntry{time: time.Now(), text: s})
+}
+
+func (g *growingLogs) Logs(ctx context.Context) (io.ReadCloser, error) {
+	return g.LogsSince(ctx, time.Time{})
+}
+
+func (g *growingLogs) LogsSince(ctx context.Context, since time.Time) (io.ReadCloser, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var b strings.Builder
+	for _, e := range g.entries {
+		if !e.time.Before(since) {
+			b.WriteString(e.text)
+		}
+	}
+	return io.NopCloser(strings.NewReader(b.String())), nil
+}
+
+// TestSuite_LogsLimitedToFailedSubtest re-runs itself in a subprocess, because
+// the scenario under test requires a failing test.
+func TestSuite_LogsLimitedToFailedSubtest(t *testing.T) {
+	if dir := os.Getenv("SUITE_LOGS_DIR"); dir != "" {
+		s := suite.New(t, suite.WithLogsDir(dir))
+		c := &growingLogs{}
+		c.write("setup\n")
+		s.Add(c)
+		s.Run("passes", func(t *testing.T) {
+			c.write("first\n")
+		})
+		s.Run("fails", func(t *testing.T) {
+			c.write("second\n")
+			t.Error("intentional failure")
+		})
+		return
+	}
+
+	dir := t.TempDir()
+	cmd := exec.Command(os.Args[0], "-test.run=^TestSuite_LogsLimitedToFailedSubtest$")
+	cmd.Env = append(os.Environ(), "SUITE_LOGS_DIR="+dir)
+	if out, err := cmd.CombinedOutput(); err == nil {
+		t.Fatalf("helper test passed, expected failure:\n%s", out)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d log files, want 1: %v", len(entries), entries)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if string(data) != "second\n" {
+		t.Errorf("log file = %q, want only the failed subtest's output %q", data, "second\n")
+	}
+}